@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/kodefluence/monorepo/exception"
 	"github.com/kodefluence/monorepo/kontext"
@@ -9,43 +11,84 @@ import (
 
 // An Adapter for golang sql
 type Adapter struct {
-	db *sql.DB
+	db         *sql.DB
+	config     Config
+	statements *statementCache
 }
 
 // Adapt adapting golang sql.DB
-func Adapt(db *sql.DB) DB {
-	return &Adapter{db: db}
+func Adapt(db *sql.DB, opts ...Option) DB {
+	var config Config
+
+	// Default value
+	config.dialect = MySQL
+	config.lockReleaseTimeout = 5 * time.Second
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Adapter{db: db, config: config, statements: newStatementCache(db, config.dialect, config.statementCacheSize)}
 }
 
 // Ping wrap sql Ping function
 func (a *Adapter) Ping(ktx kontext.Context) exception.Exception {
 	if err := a.db.Ping(); err != nil {
-		return exception.Throw(err)
+		return a.config.dialect.Translate(err)
 	}
 
 	return nil
 }
 
-// Transaction wrap mysql transaction into a bit of simpler way
-func (a *Adapter) Transaction(ktx kontext.Context, transactionKey string, f func(tx TX) exception.Exception) exception.Exception {
-	return runWithSQLAnalyzer(ktx, "db", "Transaction", func() exception.Exception {
-		tx, err := a.db.BeginTx(ktx.Ctx(), &sql.TxOptions{})
-		if err != nil {
-			return exception.Throw(err)
-		}
+// Transaction wrap database transaction into a bit of simpler way
+func (a *Adapter) Transaction(ktx kontext.Context, transactionKey string, f func(tx TX) exception.Exception, opts ...TransactionOption) exception.Exception {
+	var config TransactionConfig
 
-		adaptedTx := AdaptTXAdapter(tx)
-		if err := f(adaptedTx); err != nil {
-			_ = tx.Rollback()
-			return exception.Throw(err)
-		}
+	for _, opt := range opts {
+		opt(&config)
+	}
 
-		if err := tx.Commit(); err != nil {
-			_ = tx.Rollback()
-			return exception.Throw(err)
+	return retryTransaction(ktx, a.config.retry, a.config.dialect, transactionKey, func(attempt int) exception.Exception {
+		var adaptedTx *TXAdapter
+
+		info := QueryInfo{ExecutionLevel: "db", Function: "Transaction", TransactionKey: transactionKey, Key: transactionKey, Attempt: attempt}
+
+		exc := runWithSQLAnalyzer(ktx, a.config.analyzers, &info, func() exception.Exception {
+			ctx := ktx.Ctx()
+			if config.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, config.timeout)
+				defer cancel()
+			}
+
+			tx, err := a.db.BeginTx(ctx, &sql.TxOptions{Isolation: config.isolation, ReadOnly: config.readOnly})
+			if err != nil {
+				return a.config.dialect.Translate(err)
+			}
+
+			adaptedTx = &TXAdapter{statements: a.statements, tx: tx, transactionKey: transactionKey, attempt: attempt, config: a.config, state: &txState{statements: newTxStatements(tx)}, callbacks: &txCallbacks{}}
+			if err := f(adaptedTx); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+
+			if err := tx.Commit(); err != nil {
+				_ = tx.Rollback()
+				return a.config.dialect.Translate(err)
+			}
+
+			return nil
+		})
+
+		if adaptedTx != nil {
+			if exc == nil {
+				adaptedTx.callbacks.commit(ktx)
+			} else {
+				adaptedTx.callbacks.rollback(ktx)
+			}
 		}
 
-		return nil
+		return exc
 	})
 }
 
@@ -55,12 +98,20 @@ func (a *Adapter) ExecContext(ktx kontext.Context, queryKey, query string, args
 	var err error
 	var exc exception.Exception
 
-	exc = runWithSQLAnalyzer(ktx, "db", "ExecContext", func() exception.Exception {
-		result, err = a.db.ExecContext(ktx.Ctx(), query, args...)
+	info := QueryInfo{ExecutionLevel: "db", Function: "ExecContext", Key: queryKey, SQL: query, ArgsCount: len(args)}
+	commented := a.config.commented(ktx, queryKey, query)
+
+	exc = runWithSQLAnalyzer(ktx, a.config.analyzers, &info, func() exception.Exception {
+		if a.statements != nil {
+			result, err = a.statements.execContext(ktx.Ctx(), nil, queryKey, commented, args...)
+		} else {
+			result, err = a.db.ExecContext(ktx.Ctx(), commented, args...)
+		}
 		if err != nil {
-			return exception.Throw(err)
+			return a.config.dialect.Translate(err)
 		}
 
+		info.RowsAffected, _ = result.RowsAffected()
 		return nil
 	})
 
@@ -73,37 +124,104 @@ func (a *Adapter) QueryContext(ktx kontext.Context, queryKey, query string, args
 	var err error
 	var exc exception.Exception
 
-	exc = runWithSQLAnalyzer(ktx, "db", "QueryContext", func() exception.Exception {
-		rows, err = a.db.QueryContext(ktx.Ctx(), query, args...)
-		if err == sql.ErrNoRows {
-			return exception.Throw(err, exception.WithType(exception.NotFound))
-		} else if err != nil {
-			return exception.Throw(err)
+	info := QueryInfo{ExecutionLevel: "db", Function: "QueryContext", Key: queryKey, SQL: query, ArgsCount: len(args)}
+	commented := a.config.commented(ktx, queryKey, query)
+
+	exc = runWithSQLAnalyzer(ktx, a.config.analyzers, &info, func() exception.Exception {
+		if a.statements != nil {
+			rows, err = a.statements.queryContext(ktx.Ctx(), nil, queryKey, commented, args...)
+		} else {
+			rows, err = a.db.QueryContext(ktx.Ctx(), commented, args...)
+		}
+		if err != nil {
+			return a.config.dialect.Translate(err)
 		}
 
 		return nil
 	})
 
-	return AdaptRows(rows), exc
+	return adaptRows(rows, a.config.dialect), exc
 }
 
 // QueryRowContext wrap sql QueryRowContext function
 func (a *Adapter) QueryRowContext(ktx kontext.Context, queryKey, query string, args ...interface{}) Row {
-	var row *sql.Row
+	info := QueryInfo{ExecutionLevel: "db", Function: "QueryRowContext", Key: queryKey, SQL: query, ArgsCount: len(args)}
+	commented := a.config.commented(ktx, queryKey, query)
 
-	_ = runWithSQLAnalyzer(ktx, "db", "QueryRowContext", func() exception.Exception {
-		row = a.db.QueryRowContext(ktx.Ctx(), query, args...)
-		return nil
+	finish := startSQLAnalyzer(ktx, a.config.analyzers, &info)
+
+	var row Row
+	if a.statements != nil {
+		row = a.statements.queryRowContext(ktx.Ctx(), nil, queryKey, commented, args...)
+	} else {
+		row = adaptRow(a.db.QueryRowContext(ktx.Ctx(), commented, args...), a.config.dialect)
+	}
+
+	return &analyzedRow{Row: row, finish: finish}
+}
+
+// ExecNamed bind named parameters of query using BindNamed and execute it using ExecContext
+func (a *Adapter) ExecNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (Result, exception.Exception) {
+	query, args, exc := BindNamed(a.config.dialect, query, arg)
+	if exc != nil {
+		return AdaptResult(nil), exc
+	}
+
+	return a.ExecContext(ktx, queryKey, query, args...)
+}
+
+// QueryNamed bind named parameters of query using BindNamed and execute it using QueryContext
+func (a *Adapter) QueryNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (Rows, exception.Exception) {
+	query, args, exc := BindNamed(a.config.dialect, query, arg)
+	if exc != nil {
+		return adaptRows(nil, a.config.dialect), exc
+	}
+
+	return a.QueryContext(ktx, queryKey, query, args...)
+}
+
+// OnCommit execute f immediately with ktx since query executed outside of transaction is committed right away
+func (a *Adapter) OnCommit(ktx kontext.Context, f func(ktx kontext.Context)) {
+	f(ktx)
+}
+
+// OnRollback do nothing since query executed outside of transaction is never rolled back
+func (a *Adapter) OnRollback(f func(ktx kontext.Context)) {}
+
+// Lock acquire distributed advisory lock using MySQL GET_LOCK or Postgres advisory lock, the lock is held by single connection pinned from the pool.
+// The lock is scoped to the current database, MySQL lock name is prefixed with the database name since GET_LOCK is shared by the whole server.
+// Lock which is not acquired before the timeout is returned as exception.Conflict, negative timeout wait until the kontext is cancelled.
+// Lock is released automatically when the kontext is cancelled, dialect without advisory lock return exception wrapping ErrLockNotSupported.
+func (a *Adapter) Lock(ktx kontext.Context, name string, timeout time.Duration) (Lock, exception.Exception) {
+	var lock Lock
+
+	info := QueryInfo{ExecutionLevel: "db", Function: "Lock", Key: name}
+
+	exc := runWithSQLAnalyzer(ktx, a.config.analyzers, &info, func() exception.Exception {
+		var exc exception.Exception
+		lock, exc = acquireLock(ktx, a.db, a.config.dialect, name, timeout, a.config.lockReleaseTimeout)
+		return exc
 	})
 
-	return AdaptRow(row)
+	return lock, exc
+}
+
+// Dialect of adapted connection
+func (a *Adapter) Dialect() Dialect {
+	return a.config.dialect
 }
 
-func runWithSQLAnalyzer(ktx kontext.Context, executionLevel, function string, f func() exception.Exception) exception.Exception {
-	if err := f(); err != nil {
-		return err
+// Stats of adapted connection pool, health is only reported when the adapter is fabricated using Fabricate with WithHealthProbe
+func (a *Adapter) Stats() PoolStats {
+	stats := adaptPoolStats(a.config.instanceName, a.config.dialect, a.db.Stats())
+
+	if val, ok := instanceList.Load(instanceKey(a.config.dialect, a.config.instanceName)); ok {
+		if fabricated := val.(*instance); fabricated.db == a.db && fabricated.probe != nil {
+			stats.Health = fabricated.probe.health()
+		}
 	}
-	return nil
+
+	return stats
 }
 
 // Eject sql.DB out of db adapter
@@ -8,21 +8,23 @@ import (
 
 // RowAdapter wrap single sql row
 type RowAdapter struct {
-	sqlrow *sql.Row
+	sqlrow  *sql.Row
+	dialect Dialect
 }
 
 // AdaptRow wrap provider row
 func AdaptRow(sqlrow *sql.Row) Row {
-	return &RowAdapter{sqlrow: sqlrow}
+	return adaptRow(sqlrow, MySQL)
+}
+
+func adaptRow(sqlrow *sql.Row, dialect Dialect) Row {
+	return &RowAdapter{sqlrow: sqlrow, dialect: dialect}
 }
 
 // Scan warp default row scan function
 func (r *RowAdapter) Scan(dest ...interface{}) exception.Exception {
-	err := r.sqlrow.Scan(dest...)
-	if err == sql.ErrNoRows {
-		return exception.Throw(err, exception.WithType(exception.NotFound))
-	} else if err != nil {
-		return exception.Throw(err)
+	if err := r.sqlrow.Scan(dest...); err != nil {
+		return translate(r.dialect, err)
 	}
 
 	return nil
@@ -2,24 +2,37 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/kodefluence/monorepo/exception"
 )
 
-// RowsAdapter wrap default sql.Rows struct
+// errRowsClosed is returned when scanning rows of failed query, the same as database/sql error of closed rows
+var errRowsClosed = errors.New("sql: Rows are closed")
+
+// RowsAdapter wrap default sql.Rows struct, rows returned with exception of failed query has no sql.Rows and behave as closed rows
 type RowsAdapter struct {
 	*sql.Rows
+	dialect Dialect
 }
 
 // AdaptRows adapting sql.Rows into adapter.Rows
 func AdaptRows(rows *sql.Rows) Rows {
-	return &RowsAdapter{Rows: rows}
+	return adaptRows(rows, MySQL)
+}
+
+func adaptRows(rows *sql.Rows, dialect Dialect) Rows {
+	return &RowsAdapter{Rows: rows, dialect: dialect}
 }
 
 // Close rows
 func (r *RowsAdapter) Close() exception.Exception {
+	if r.Rows == nil {
+		return nil
+	}
+
 	if err := r.Rows.Close(); err != nil {
-		return exception.Throw(err)
+		return translate(r.dialect, err)
 	}
 
 	return nil
@@ -30,18 +43,36 @@ func (r *RowsAdapter) Columns() ([]string, exception.Exception) {
 	var columns []string
 	var err error
 
+	if r.Rows == nil {
+		return nil, translate(r.dialect, errRowsClosed)
+	}
+
 	columns, err = r.Rows.Columns()
 	if err != nil {
-		return columns, exception.Throw(err)
+		return columns, translate(r.dialect, err)
 	}
 
 	return columns, nil
 }
 
+// Next prepare the next result row, it return false when there is no more row
+func (r *RowsAdapter) Next() bool {
+	return r.Rows != nil && r.Rows.Next()
+}
+
+// NextResultSet prepare the next result set, it return false when there is no more result set
+func (r *RowsAdapter) NextResultSet() bool {
+	return r.Rows != nil && r.Rows.NextResultSet()
+}
+
 // Err return rows error
 func (r *RowsAdapter) Err() exception.Exception {
+	if r.Rows == nil {
+		return nil
+	}
+
 	if err := r.Rows.Err(); err != nil {
-		return exception.Throw(err)
+		return translate(r.dialect, err)
 	}
 
 	return nil
@@ -49,8 +80,12 @@ func (r *RowsAdapter) Err() exception.Exception {
 
 // Scan row
 func (r *RowsAdapter) Scan(dest ...interface{}) exception.Exception {
+	if r.Rows == nil {
+		return translate(r.dialect, errRowsClosed)
+	}
+
 	if err := r.Rows.Scan(dest...); err != nil {
-		return exception.Throw(err)
+		return translate(r.dialect, err)
 	}
 
 	return nil
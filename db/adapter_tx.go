@@ -2,6 +2,8 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
+	"sync"
 
 	"github.com/kodefluence/monorepo/exception"
 	"github.com/kodefluence/monorepo/kontext"
@@ -9,12 +11,118 @@ import (
 
 // A TXAdapter adapater for golang sql
 type TXAdapter struct {
-	tx *sql.Tx
+	tx             *sql.Tx
+	transactionKey string
+	attempt        int
+	config         Config
+	state          *txState
+	callbacks      *txCallbacks
+	statements     *statementCache
 }
 
-// AdaptTXAdapter do adapting mysql transaction
-func AdaptTXAdapter(tx *sql.Tx) *TXAdapter {
-	return &TXAdapter{tx: tx}
+// txState is shared between transaction and its nested transactions
+type txState struct {
+	savepointSequence int
+	statements        *txStatements
+}
+
+// txCallbacks registered by OnCommit and OnRollback on each transaction level
+type txCallbacks struct {
+	mutex      sync.Mutex
+	onCommit   []func(ktx kontext.Context)
+	onRollback []func(ktx kontext.Context)
+}
+
+func (c *txCallbacks) commit(ktx kontext.Context) {
+	for _, f := range c.onCommit {
+		f(ktx)
+	}
+}
+
+func (c *txCallbacks) rollback(ktx kontext.Context) {
+	for _, f := range c.onRollback {
+		f(ktx)
+	}
+}
+
+// mergeInto hand over callbacks of released nested transaction into its parent
+func (c *txCallbacks) mergeInto(parent *txCallbacks) {
+	parent.mutex.Lock()
+	defer parent.mutex.Unlock()
+
+	parent.onCommit = append(parent.onCommit, c.onCommit...)
+	parent.onRollback = append(parent.onRollback, c.onRollback...)
+}
+
+// AdaptTXAdapter do adapting database transaction
+func AdaptTXAdapter(tx *sql.Tx, opts ...Option) *TXAdapter {
+	var config Config
+
+	// Default value
+	config.dialect = MySQL
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &TXAdapter{tx: tx, config: config, state: &txState{}, callbacks: &txCallbacks{}}
+}
+
+// OnCommit register f to be executed after the transaction is committed. Registered in nested transaction, f is discarded when the nested transaction is rolled back.
+// Callbacks only executed when the transaction is managed by Transaction function, f receive kontext passed into Transaction.
+func (t *TXAdapter) OnCommit(ktx kontext.Context, f func(ktx kontext.Context)) {
+	t.callbacks.mutex.Lock()
+	defer t.callbacks.mutex.Unlock()
+
+	t.callbacks.onCommit = append(t.callbacks.onCommit, f)
+}
+
+// OnRollback register f to be executed after the transaction is rolled back. Registered in nested transaction, f is executed when the nested transaction is rolled back.
+// Callbacks only executed when the transaction is managed by Transaction function.
+func (t *TXAdapter) OnRollback(f func(ktx kontext.Context)) {
+	t.callbacks.mutex.Lock()
+	defer t.callbacks.mutex.Unlock()
+
+	t.callbacks.onRollback = append(t.callbacks.onRollback, f)
+}
+
+// Transaction create nested transaction using savepoint. When f return exception only changes made inside f is rolled back.
+// Transaction options is ignored since nested transaction follow its parent transaction.
+func (t *TXAdapter) Transaction(ktx kontext.Context, transactionKey string, f func(tx TX) exception.Exception, opts ...TransactionOption) exception.Exception {
+	var nestedTx *TXAdapter
+
+	info := QueryInfo{ExecutionLevel: "tx", Function: "Transaction", TransactionKey: transactionKey, Attempt: t.attempt, Key: transactionKey}
+
+	exc := runWithSQLAnalyzer(ktx, t.config.analyzers, &info, func() exception.Exception {
+		t.state.savepointSequence++
+		savepoint := fmt.Sprintf("sp_%d", t.state.savepointSequence)
+
+		if _, err := t.tx.ExecContext(ktx.Ctx(), "SAVEPOINT "+savepoint); err != nil {
+			return t.config.dialect.Translate(err)
+		}
+
+		nestedTx = &TXAdapter{statements: t.statements, tx: t.tx, transactionKey: transactionKey, attempt: t.attempt, config: t.config, state: t.state, callbacks: &txCallbacks{}}
+		if err := f(nestedTx); err != nil {
+			_, _ = t.tx.ExecContext(ktx.Ctx(), "ROLLBACK TO SAVEPOINT "+savepoint)
+			return err
+		}
+
+		if _, err := t.tx.ExecContext(ktx.Ctx(), "RELEASE SAVEPOINT "+savepoint); err != nil {
+			return t.config.dialect.Translate(err)
+		}
+
+		return nil
+	})
+
+	if nestedTx != nil {
+		if exc == nil {
+			nestedTx.callbacks.mergeInto(t.callbacks)
+		} else {
+			nestedTx.callbacks.rollback(ktx)
+		}
+	}
+
+	return exc
 }
 
 // ExecContext wrap sql ExecContext function
@@ -23,12 +131,20 @@ func (t *TXAdapter) ExecContext(ctx kontext.Context, queryKey, query string, arg
 	var err error
 	var exc exception.Exception
 
-	exc = runWithSQLAnalyzer(ctx, "tx", "ExecContext", func() exception.Exception {
-		result, err = t.tx.ExecContext(ctx.Ctx(), query, args...)
+	info := QueryInfo{ExecutionLevel: "tx", Function: "ExecContext", TransactionKey: t.transactionKey, Attempt: t.attempt, Key: queryKey, SQL: query, ArgsCount: len(args)}
+	commented := t.config.commented(ctx, queryKey, query)
+
+	exc = runWithSQLAnalyzer(ctx, t.config.analyzers, &info, func() exception.Exception {
+		if t.statements != nil {
+			result, err = t.statements.execContext(ctx.Ctx(), t.state.statements, queryKey, commented, args...)
+		} else {
+			result, err = t.tx.ExecContext(ctx.Ctx(), commented, args...)
+		}
 		if err != nil {
-			return exception.Throw(err)
+			return t.config.dialect.Translate(err)
 		}
 
+		info.RowsAffected, _ = result.RowsAffected()
 		return nil
 	})
 
@@ -41,28 +157,63 @@ func (t *TXAdapter) QueryContext(ctx kontext.Context, queryKey, query string, ar
 	var err error
 	var exc exception.Exception
 
-	exc = runWithSQLAnalyzer(ctx, "tx", "QueryContext", func() exception.Exception {
-		rows, err = t.tx.QueryContext(ctx.Ctx(), query, args...)
-		if err == sql.ErrNoRows {
-			return exception.Throw(err, exception.WithType(exception.NotFound))
-		} else if err != nil {
-			return exception.Throw(err)
+	info := QueryInfo{ExecutionLevel: "tx", Function: "QueryContext", TransactionKey: t.transactionKey, Attempt: t.attempt, Key: queryKey, SQL: query, ArgsCount: len(args)}
+	commented := t.config.commented(ctx, queryKey, query)
+
+	exc = runWithSQLAnalyzer(ctx, t.config.analyzers, &info, func() exception.Exception {
+		if t.statements != nil {
+			rows, err = t.statements.queryContext(ctx.Ctx(), t.state.statements, queryKey, commented, args...)
+		} else {
+			rows, err = t.tx.QueryContext(ctx.Ctx(), commented, args...)
+		}
+		if err != nil {
+			return t.config.dialect.Translate(err)
 		}
 
 		return nil
 	})
 
-	return AdaptRows(rows), exc
+	return adaptRows(rows, t.config.dialect), exc
 }
 
 // QueryRowContext wrap sql QueryRowContext function
 func (t *TXAdapter) QueryRowContext(ctx kontext.Context, queryKey, query string, args ...interface{}) Row {
-	var row *sql.Row
+	info := QueryInfo{ExecutionLevel: "tx", Function: "QueryRowContext", TransactionKey: t.transactionKey, Attempt: t.attempt, Key: queryKey, SQL: query, ArgsCount: len(args)}
+	commented := t.config.commented(ctx, queryKey, query)
 
-	_ = runWithSQLAnalyzer(ctx, "tx", "QueryRowContext", func() exception.Exception {
-		row = t.tx.QueryRowContext(ctx.Ctx(), query, args...)
-		return nil
-	})
+	finish := startSQLAnalyzer(ctx, t.config.analyzers, &info)
+
+	var row Row
+	if t.statements != nil {
+		row = t.statements.queryRowContext(ctx.Ctx(), t.state.statements, queryKey, commented, args...)
+	} else {
+		row = adaptRow(t.tx.QueryRowContext(ctx.Ctx(), commented, args...), t.config.dialect)
+	}
+
+	return &analyzedRow{Row: row, finish: finish}
+}
+
+// ExecNamed bind named parameters of query using BindNamed and execute it using ExecContext
+func (t *TXAdapter) ExecNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (Result, exception.Exception) {
+	query, args, exc := BindNamed(t.config.dialect, query, arg)
+	if exc != nil {
+		return AdaptResult(nil), exc
+	}
+
+	return t.ExecContext(ctx, queryKey, query, args...)
+}
+
+// QueryNamed bind named parameters of query using BindNamed and execute it using QueryContext
+func (t *TXAdapter) QueryNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (Rows, exception.Exception) {
+	query, args, exc := BindNamed(t.config.dialect, query, arg)
+	if exc != nil {
+		return adaptRows(nil, t.config.dialect), exc
+	}
+
+	return t.QueryContext(ctx, queryKey, query, args...)
+}
 
-	return AdaptRow(row)
+// Dialect of adapted transaction
+func (t *TXAdapter) Dialect() Dialect {
+	return t.config.dialect
 }
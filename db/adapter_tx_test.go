@@ -1,9 +1,11 @@
 package db_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/kodefluence/monorepo/db"
@@ -254,4 +256,238 @@ func TestTx(t *testing.T) {
 			assert.Nil(t, mockDB.ExpectationsWereMet())
 		})
 	})
+	t.Run("Transaction", func(t *testing.T) {
+		t.Run("When nested transaction succeed the savepoint is released", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`SAVEPOINT sp_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`insert into users`).WillReturnResult(sqlmock.NewResult(1, 1))
+			mockDB.ExpectExec(`RELEASE SAVEPOINT sp_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectCommit()
+
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				return tx.Transaction(ktx, "nested-transaction-test", func(tx db.TX) exception.Exception {
+					_, err := tx.ExecContext(ktx, "test-query-1", "insert into users")
+					return err
+				})
+			})
+
+			assert.Nil(t, err)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When nested transaction failed only the savepoint is rolled back", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`SAVEPOINT sp_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`insert into users`).WillReturnError(errors.New("unexpected error"))
+			mockDB.ExpectExec(`ROLLBACK TO SAVEPOINT sp_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`SAVEPOINT sp_2`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`insert into audits`).WillReturnResult(sqlmock.NewResult(1, 1))
+			mockDB.ExpectExec(`RELEASE SAVEPOINT sp_2`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectCommit()
+
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				exc := tx.Transaction(ktx, "nested-transaction-test", func(tx db.TX) exception.Exception {
+					_, err := tx.ExecContext(ktx, "test-query-1", "insert into users")
+					return err
+				})
+				assert.NotNil(t, exc)
+
+				return tx.Transaction(ktx, "nested-transaction-test", func(tx db.TX) exception.Exception {
+					_, err := tx.ExecContext(ktx, "test-query-2", "insert into audits")
+					return err
+				})
+			})
+
+			assert.Nil(t, err)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When creating savepoint failed then it will return error", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`SAVEPOINT sp_1`).WillReturnError(errors.New("unexpected error"))
+			mockDB.ExpectRollback()
+
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				return tx.Transaction(ktx, "nested-transaction-test", func(tx db.TX) exception.Exception {
+					return nil
+				})
+			})
+
+			assert.NotNil(t, err)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("TransactionOption", func(t *testing.T) {
+		t.Run("When transaction started with isolation level and read only it will run the transaction", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mockDB.ExpectCommit()
+
+			adapter := db.Adapt(sqldb)
+			err = adapter.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				var x int
+				return tx.QueryRowContext(ktx, "test-query-1", "select id from users").Scan(&x)
+			}, db.WithIsolationLevel(sql.LevelRepeatableRead), db.WithReadOnly())
+
+			assert.Nil(t, err)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When transaction exceeding the timeout it will be rolled back", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`insert into users`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				_, err := tx.ExecContext(ktx, "test-query-1", "insert into users")
+				time.Sleep(20 * time.Millisecond)
+				return err
+			}, db.WithTimeout(5*time.Millisecond))
+
+			assert.NotNil(t, err)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("OnCommit and OnRollback", func(t *testing.T) {
+		t.Run("When transaction committed only commit callbacks is executed", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`insert into users`).WillReturnResult(sqlmock.NewResult(1, 1))
+			mockDB.ExpectCommit()
+
+			var events []string
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				tx.OnCommit(ktx, func(ktx kontext.Context) { events = append(events, "commit-1") })
+				tx.OnRollback(func(ktx kontext.Context) { events = append(events, "rollback") })
+				tx.OnCommit(ktx, func(ktx kontext.Context) { events = append(events, "commit-2") })
+
+				_, err := tx.ExecContext(ktx, "test-query-1", "insert into users")
+				assert.Equal(t, 0, len(events))
+				return err
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"commit-1", "commit-2"}, events)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When commit failed only rollback callbacks is executed", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectCommit().WillReturnError(errors.New("unexpected error"))
+
+			var events []string
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				tx.OnCommit(ktx, func(ktx kontext.Context) { events = append(events, "commit") })
+				tx.OnRollback(func(ktx kontext.Context) { events = append(events, "rollback") })
+				return nil
+			})
+
+			assert.NotNil(t, err)
+			assert.Equal(t, []string{"rollback"}, events)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When nested transaction rolled back its commit callbacks is discarded", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`SAVEPOINT sp_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`RELEASE SAVEPOINT sp_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`SAVEPOINT sp_2`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(`ROLLBACK TO SAVEPOINT sp_2`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectCommit()
+
+			var events []string
+			sql := db.Adapt(sqldb)
+			err = sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+				_ = tx.Transaction(ktx, "released", func(tx db.TX) exception.Exception {
+					tx.OnCommit(ktx, func(ktx kontext.Context) { events = append(events, "released-commit") })
+					return nil
+				})
+
+				_ = tx.Transaction(ktx, "rolled-back", func(tx db.TX) exception.Exception {
+					tx.OnCommit(ktx, func(ktx kontext.Context) { events = append(events, "rolled-back-commit") })
+					tx.OnRollback(func(ktx kontext.Context) { events = append(events, "rolled-back-rollback") })
+					return exception.Throw(errors.New("unexpected error"))
+				})
+
+				return nil
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"rolled-back-rollback", "released-commit"}, events)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When registered outside of transaction commit callback is executed immediately with the caller kontext", func(t *testing.T) {
+			sqldb, _, _ := sqlmock.New()
+			defer sqldb.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			caller := kontext.Fabricate(kontext.WithDefaultContext(ctx))
+
+			var events []string
+			var received kontext.Context
+			sql := db.Adapt(sqldb)
+			sql.OnCommit(caller, func(ktx kontext.Context) {
+				events = append(events, "commit")
+				received = ktx
+			})
+			sql.OnRollback(func(ktx kontext.Context) { events = append(events, "rollback") })
+
+			assert.Equal(t, []string{"commit"}, events)
+			assert.Same(t, caller, received)
+		})
+	})
 }
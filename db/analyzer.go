@@ -0,0 +1,101 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Analyzer hook into every query and transaction executed by Adapter and TXAdapter.
+// It can be used to plug logging, metrics or tracing without wrapping every repository call.
+type Analyzer interface {
+	// Before called right before the query or transaction is executed
+	Before(ktx kontext.Context, info QueryInfo)
+
+	// After called right after the query or transaction is executed, it carry duration, rows affected and resulting exception.
+	// For QueryRowContext it is called when the row is scanned so the exception such as sql.ErrNoRows is included.
+	After(ktx kontext.Context, info QueryInfo)
+}
+
+// QueryInfo carry information of executed query or transaction into Analyzer
+type QueryInfo struct {
+	// ExecutionLevel is either "db" or "tx"
+	ExecutionLevel string
+
+	// Function is the adapter function name, e.g. ExecContext, QueryContext, QueryRowContext or Transaction
+	Function string
+
+	// TransactionKey is filled when the query executed inside of transaction or when Function is Transaction
+	TransactionKey string
+
+	// Key is the queryKey given by the caller, for Transaction it is the transactionKey
+	Key string
+
+	// SQL text of the query, empty for Transaction
+	SQL string
+
+	ArgsCount int
+
+	// Attempt of the transaction started from 1, it is bigger than 1 when the transaction is retried and zero outside of transaction
+	Attempt int
+
+	StartedAt time.Time
+
+	// Duration only available in After
+	Duration time.Duration
+
+	// RowsAffected only filled by ExecContext
+	RowsAffected int64
+
+	// Exception only available in After, nil if the execution succeed
+	Exception exception.Exception
+}
+
+// WithAnalyzer adapt connection with analyzer, analyzers called in the same order as they are registered
+func WithAnalyzer(analyzers ...Analyzer) Option {
+	return func(c *Config) {
+		c.analyzers = append(c.analyzers, analyzers...)
+	}
+}
+
+func runWithSQLAnalyzer(ktx kontext.Context, analyzers []Analyzer, info *QueryInfo, f func() exception.Exception) exception.Exception {
+	finish := startSQLAnalyzer(ktx, analyzers, info)
+
+	err := f()
+	finish(err)
+
+	return err
+}
+
+// startSQLAnalyzer call Before of analyzers and return function calling After with the resulting exception,
+// used directly when the result is only known later such as QueryRowContext whose exception is returned by Scan
+func startSQLAnalyzer(ktx kontext.Context, analyzers []Analyzer, info *QueryInfo) func(exc exception.Exception) {
+	info.StartedAt = time.Now()
+	for _, analyzer := range analyzers {
+		analyzer.Before(ktx, *info)
+	}
+
+	return func(exc exception.Exception) {
+		info.Duration = time.Since(info.StartedAt)
+		info.Exception = exc
+		for _, analyzer := range analyzers {
+			analyzer.After(ktx, *info)
+		}
+	}
+}
+
+// analyzedRow call After of analyzers on the first Scan, since the exception of QueryRowContext is only known when it is scanned
+type analyzedRow struct {
+	Row
+	once   sync.Once
+	finish func(exc exception.Exception)
+}
+
+func (r *analyzedRow) Scan(dest ...interface{}) exception.Exception {
+	exc := r.Row.Scan(dest...)
+	r.once.Do(func() { r.finish(exc) })
+
+	return exc
+}
@@ -0,0 +1,144 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordAnalyzer struct {
+	before []db.QueryInfo
+	after  []db.QueryInfo
+}
+
+func (r *recordAnalyzer) Before(ktx kontext.Context, info db.QueryInfo) {
+	r.before = append(r.before, info)
+}
+
+func (r *recordAnalyzer) After(ktx kontext.Context, info db.QueryInfo) {
+	r.after = append(r.after, info)
+}
+
+func TestAnalyzer(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When query executed from db it will call before and after hooks", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectExec(`update users set name = \? where id = \?`).WithArgs("john", 1).WillReturnResult(sqlmock.NewResult(0, 3))
+
+		analyzer := &recordAnalyzer{}
+		sql := db.Adapt(sqldb, db.WithAnalyzer(analyzer))
+		_, exc := sql.ExecContext(ktx, "update-user-name", "update users set name = ? where id = ?", "john", 1)
+		assert.Nil(t, exc)
+
+		assert.Equal(t, 1, len(analyzer.before))
+		assert.Equal(t, 1, len(analyzer.after))
+		assert.Equal(t, "db", analyzer.after[0].ExecutionLevel)
+		assert.Equal(t, "ExecContext", analyzer.after[0].Function)
+		assert.Equal(t, "update-user-name", analyzer.after[0].Key)
+		assert.Equal(t, "update users set name = ? where id = ?", analyzer.after[0].SQL)
+		assert.Equal(t, 2, analyzer.after[0].ArgsCount)
+		assert.Equal(t, int64(3), analyzer.after[0].RowsAffected)
+		assert.Nil(t, analyzer.after[0].Exception)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When query failed the after hook will carry the exception", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectQuery(`select id from users`).WillReturnError(errors.New("unexpected error"))
+
+		analyzer := &recordAnalyzer{}
+		sql := db.Adapt(sqldb, db.WithAnalyzer(analyzer))
+		_, exc := sql.QueryContext(ktx, "select-users", "select id from users")
+		assert.NotNil(t, exc)
+
+		assert.Equal(t, 1, len(analyzer.after))
+		assert.Equal(t, exc, analyzer.after[0].Exception)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When row query is scanned the after hook will carry the scan exception", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`select id from users`).WillReturnError(errors.New("connection reset"))
+		mockDB.ExpectRollback()
+
+		analyzer := &recordAnalyzer{}
+		sql := db.Adapt(sqldb, db.WithAnalyzer(analyzer))
+
+		var id int
+		row := sql.QueryRowContext(ktx, "find-user", "select id from users")
+		assert.Equal(t, 1, len(analyzer.before))
+		assert.Equal(t, 0, len(analyzer.after))
+
+		exc := row.Scan(&id)
+		assert.Equal(t, exception.NotFound, exc.Type())
+		assert.Equal(t, 1, len(analyzer.after))
+		assert.Equal(t, "QueryRowContext", analyzer.after[0].Function)
+		assert.Equal(t, exc, analyzer.after[0].Exception)
+
+		_ = row.Scan(&id)
+		assert.Equal(t, 1, len(analyzer.after))
+
+		txExc := sql.Transaction(ktx, "find-user-tx", func(tx db.TX) exception.Exception {
+			return tx.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id)
+		})
+		assert.NotNil(t, txExc)
+		assert.Equal(t, "tx", analyzer.after[1].ExecutionLevel)
+		assert.Equal(t, txExc, analyzer.after[1].Exception)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When query executed inside transaction it will carry the transaction key", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`select id from users where id = \?`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mockDB.ExpectCommit()
+
+		analyzer := &recordAnalyzer{}
+		sql := db.Adapt(sqldb, db.WithAnalyzer(analyzer))
+		exc := sql.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+			var id int
+			return tx.QueryRowContext(ktx, "select-user", "select id from users where id = ?", 1).Scan(&id)
+		})
+		assert.Nil(t, exc)
+
+		assert.Equal(t, 2, len(analyzer.after))
+
+		assert.Equal(t, "tx", analyzer.after[0].ExecutionLevel)
+		assert.Equal(t, "QueryRowContext", analyzer.after[0].Function)
+		assert.Equal(t, "transaction-test", analyzer.after[0].TransactionKey)
+		assert.Equal(t, "select-user", analyzer.after[0].Key)
+
+		assert.Equal(t, "db", analyzer.after[1].ExecutionLevel)
+		assert.Equal(t, "Transaction", analyzer.after[1].Function)
+		assert.Equal(t, "transaction-test", analyzer.after[1].Key)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
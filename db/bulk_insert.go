@@ -0,0 +1,211 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// BulkInsertConfig carry bulk insert config
+type BulkInsertConfig struct {
+	maxPacketSize   int
+	maxPlaceholders int
+	updateColumns   []string
+	conflictColumns []string
+}
+
+// BulkInsertOption when executing bulk insert
+type BulkInsertOption func(*BulkInsertConfig)
+
+// WithMaxPacketSize limit estimated size in bytes of each insert statement including its args, set it below mysql max_allowed_packet. Default to 4MB
+func WithMaxPacketSize(maxPacketSize int) BulkInsertOption {
+	return func(c *BulkInsertConfig) {
+		c.maxPacketSize = maxPacketSize
+	}
+}
+
+// WithMaxPlaceholders limit placeholders of each insert statement, default to Dialect MaxPlaceholders
+func WithMaxPlaceholders(maxPlaceholders int) BulkInsertOption {
+	return func(c *BulkInsertConfig) {
+		c.maxPlaceholders = maxPlaceholders
+	}
+}
+
+// WithUpsert update given columns with the inserted value when the row is duplicated using Dialect Upsert clause.
+// MySQL use `ON DUPLICATE KEY UPDATE`, Postgres and SQLite use `ON CONFLICT DO UPDATE` which require WithConflictColumns.
+func WithUpsert(updateColumns ...string) BulkInsertOption {
+	return func(c *BulkInsertConfig) {
+		c.updateColumns = updateColumns
+	}
+}
+
+// WithConflictColumns set unique columns used as conflict target of Postgres and SQLite upsert, it is ignored in MySQL
+func WithConflictColumns(conflictColumns ...string) BulkInsertOption {
+	return func(c *BulkInsertConfig) {
+		c.conflictColumns = conflictColumns
+	}
+}
+
+// BulkInsert insert rows into table using multi rows insert statement, rows is split into several statements based on packet size and placeholders limit.
+// Each row must have the same length as columns, table and columns is written into query as it is so it must not come from user input.
+// Every statement is executed with the same queryKey, pass DB transaction as tx to make the whole insert atomic.
+// Result RowsAffected is the total of every statement and LastInsertId is taken from the first statement.
+func BulkInsert(ktx kontext.Context, tx TX, queryKey, table string, columns []string, rows [][]interface{}, opts ...BulkInsertOption) (Result, exception.Exception) {
+	var config BulkInsertConfig
+
+	dialect := tx.Dialect()
+
+	// Default value
+	config.maxPacketSize = 4 << 20
+	config.maxPlaceholders = dialect.MaxPlaceholders()
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	result := &bulkResult{}
+
+	if len(columns) == 0 {
+		return result, exception.Throw(fmt.Errorf("bulk insert into %s require columns", table), exception.WithType(exception.BadInput))
+	}
+
+	if len(columns) > config.maxPlaceholders {
+		return result, exception.Throw(fmt.Errorf("bulk insert into %s has %d columns, exceeding %d placeholders", table, len(columns), config.maxPlaceholders), exception.WithType(exception.BadInput))
+	}
+
+	suffix, exc := upsertClause(dialect, config)
+	if exc != nil {
+		return result, exc
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+
+	start := 0
+	size := len(prefix) + len(suffix)
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return result, exception.Throw(fmt.Errorf("bulk insert into %s row %d has %d values, expected %d", table, i, len(row), len(columns)), exception.WithType(exception.BadInput))
+		}
+
+		rowSize := estimateRowSize(row)
+		if i > start && (size+rowSize > config.maxPacketSize || (i-start+1)*len(columns) > config.maxPlaceholders) {
+			if exc := result.exec(ktx, tx, queryKey, prefix, suffix, columns, rows[start:i]); exc != nil {
+				return result, exc
+			}
+
+			start = i
+			size = len(prefix) + len(suffix)
+		}
+
+		size += rowSize
+	}
+
+	if start < len(rows) {
+		if exc := result.exec(ktx, tx, queryKey, prefix, suffix, columns, rows[start:]); exc != nil {
+			return result, exc
+		}
+	}
+
+	return result, nil
+}
+
+func upsertClause(dialect Dialect, config BulkInsertConfig) (string, exception.Exception) {
+	if len(config.updateColumns) == 0 {
+		return "", nil
+	}
+
+	clause, exc := dialect.Upsert(config.conflictColumns, config.updateColumns)
+	if exc != nil {
+		return "", exc
+	}
+
+	return " " + clause, nil
+}
+
+// estimateRowSize estimate bytes of a row written in the statement, args is counted as interpolated since mysql dialect interpolate params
+func estimateRowSize(row []interface{}) int {
+	size := 4
+	for _, value := range row {
+		if valuer, ok := value.(driver.Valuer); ok {
+			value, _ = valuer.Value()
+		}
+
+		switch v := value.(type) {
+		case string:
+			size += 2*len(v) + 4
+		case []byte:
+			size += 2*len(v) + 4
+		case time.Time:
+			size += 32
+		default:
+			size += 24
+		}
+	}
+
+	return size
+}
+
+type bulkResult struct {
+	lastInsertID    int64
+	lastInsertIDExc exception.Exception
+	rowsAffected    int64
+	rowsAffectedExc exception.Exception
+	statements      int
+}
+
+func (r *bulkResult) exec(ktx kontext.Context, tx TX, queryKey, prefix, suffix string, columns []string, rows [][]interface{}) exception.Exception {
+	var builder strings.Builder
+	args := make([]interface{}, 0, len(rows)*len(columns))
+
+	builder.WriteString(prefix)
+	for i, row := range rows {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteByte('(')
+		for j := range row {
+			if j > 0 {
+				builder.WriteString(", ")
+			}
+
+			args = append(args, row[j])
+			builder.WriteString(tx.Dialect().Placeholder(len(args)))
+		}
+		builder.WriteByte(')')
+	}
+	builder.WriteString(suffix)
+
+	result, exc := tx.ExecContext(ktx, queryKey, builder.String(), args...)
+	if exc != nil {
+		return exc
+	}
+
+	if r.statements == 0 {
+		r.lastInsertID, r.lastInsertIDExc = result.LastInsertId()
+	}
+
+	rowsAffected, exc := result.RowsAffected()
+	if exc != nil && r.rowsAffectedExc == nil {
+		r.rowsAffectedExc = exc
+	}
+
+	r.rowsAffected += rowsAffected
+	r.statements++
+
+	return nil
+}
+
+// LastInsertId of the first executed statement
+func (r *bulkResult) LastInsertId() (int64, exception.Exception) {
+	return r.lastInsertID, r.lastInsertIDExc
+}
+
+// RowsAffected total of every executed statement
+func (r *bulkResult) RowsAffected() (int64, exception.Exception) {
+	return r.rowsAffected, r.rowsAffectedExc
+}
@@ -0,0 +1,164 @@
+package db_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+// cockroachDialect is third party dialect speaking postgres protocol with its own placeholders limit
+type cockroachDialect struct {
+	db.Dialect
+}
+
+func (cockroachDialect) Name() string {
+	return "cockroach"
+}
+
+func (cockroachDialect) MaxPlaceholders() int {
+	return 2
+}
+
+func TestBulkInsert(t *testing.T) {
+	ktx := kontext.Fabricate()
+	rows := [][]interface{}{{1, "john"}, {2, "jane"}, {3, "doe"}}
+
+	t.Run("When rows exceed placeholders limit it will be split into several statements", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\?, \?\), \(\?, \?\)$`).WithArgs(1, "john", 2, "jane").WillReturnResult(sqlmock.NewResult(1, 2))
+		mockDB.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\?, \?\)$`).WithArgs(3, "doe").WillReturnResult(sqlmock.NewResult(3, 1))
+
+		result, exc := db.BulkInsert(ktx, db.Adapt(sqldb), "insert-users", "users", []string{"id", "name"}, rows, db.WithMaxPlaceholders(4))
+		assert.Nil(t, exc)
+
+		lastInsertID, exc := result.LastInsertId()
+		assert.Nil(t, exc)
+		assert.Equal(t, int64(1), lastInsertID)
+
+		rowsAffected, exc := result.RowsAffected()
+		assert.Nil(t, exc)
+		assert.Equal(t, int64(3), rowsAffected)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When rows exceed packet size it will be split into several statements inside transaction", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		name := strings.Repeat("a", 100)
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\?, \?\) ON DUPLICATE KEY UPDATE name = VALUES\(name\)$`).WithArgs(1, name).WillReturnResult(sqlmock.NewResult(1, 1))
+		mockDB.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\?, \?\) ON DUPLICATE KEY UPDATE name = VALUES\(name\)$`).WithArgs(2, name).WillReturnResult(sqlmock.NewResult(2, 2))
+		mockDB.ExpectCommit()
+
+		exc := db.Adapt(sqldb).Transaction(ktx, "upsert-users", func(tx db.TX) exception.Exception {
+			result, exc := db.BulkInsert(ktx, tx, "upsert-users", "users", []string{"id", "name"}, [][]interface{}{{1, name}, {2, name}}, db.WithMaxPacketSize(300), db.WithUpsert("name"))
+			if exc != nil {
+				return exc
+			}
+
+			rowsAffected, _ := result.RowsAffected()
+			assert.Equal(t, int64(3), rowsAffected)
+			return nil
+		})
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When dialect is postgres it will upsert using on conflict", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\$1, \$2\), \(\$3, \$4\), \(\$5, \$6\) ON CONFLICT \(id\) DO UPDATE SET name = EXCLUDED.name$`).WillReturnResult(sqlmock.NewResult(0, 3))
+
+		adapter := db.Adapt(sqldb, db.WithDialect(db.Postgres))
+		_, exc := db.BulkInsert(ktx, adapter, "upsert-users", "users", []string{"id", "name"}, rows, db.WithUpsert("name"), db.WithConflictColumns("id"))
+		assert.Nil(t, exc)
+
+		_, exc = db.BulkInsert(ktx, adapter, "upsert-users", "users", []string{"id", "name"}, rows, db.WithUpsert("name"))
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When dialect is not built in it will use the dialect upsert clause and placeholders limit", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		for range rows {
+			mockDB.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\$1, \$2\) ON CONFLICT \(id\) DO UPDATE SET name = EXCLUDED.name$`).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		adapter := db.Adapt(sqldb, db.WithDialect(cockroachDialect{Dialect: db.Postgres}))
+		result, exc := db.BulkInsert(ktx, adapter, "upsert-users", "users", []string{"id", "name"}, rows, db.WithUpsert("name"), db.WithConflictColumns("id"))
+		assert.Nil(t, exc)
+
+		rowsAffected, _ := result.RowsAffected()
+		assert.Equal(t, int64(3), rowsAffected)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When input is invalid it will return bad input exception", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		adapter := db.Adapt(sqldb)
+
+		_, exc := db.BulkInsert(ktx, adapter, "insert-users", "users", nil, rows)
+		assert.Equal(t, exception.BadInput, exc.Type())
+
+		_, exc = db.BulkInsert(ktx, adapter, "insert-users", "users", []string{"id", "name"}, [][]interface{}{{1}})
+		assert.Equal(t, exception.BadInput, exc.Type())
+
+		_, exc = db.BulkInsert(ktx, adapter, "insert-users", "users", []string{"id", "name"}, rows, db.WithMaxPlaceholders(1))
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When executed in sqlite it will insert and upsert every rows", func(t *testing.T) {
+		sqldb, exc := sqlite.Fabricate("bulk-insert-test", db.Config{Name: sqlite.Memory})
+		assert.Nil(t, exc)
+		defer sqldb.Eject().Close()
+
+		_, exc = sqldb.ExecContext(ktx, "create-users", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+		assert.Nil(t, exc)
+
+		result, exc := db.BulkInsert(ktx, sqldb, "insert-users", "users", []string{"id", "name"}, rows, db.WithMaxPlaceholders(2))
+		assert.Nil(t, exc)
+		rowsAffected, _ := result.RowsAffected()
+		assert.Equal(t, int64(3), rowsAffected)
+
+		_, exc = db.BulkInsert(ktx, sqldb, "upsert-users", "users", []string{"id", "name"}, [][]interface{}{{1, "johnny"}, {4, "smith"}}, db.WithUpsert("name"), db.WithConflictColumns("id"))
+		assert.Nil(t, exc)
+
+		userRows, exc := sqldb.QueryContext(ktx, "list-users", "SELECT name FROM users ORDER BY id")
+		assert.Nil(t, exc)
+
+		names, exc := db.ScanAll[string](userRows)
+		assert.Nil(t, exc)
+		assert.Equal(t, []string{"johnny", "jane", "doe", "smith"}, names)
+	})
+}
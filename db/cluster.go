@@ -0,0 +1,322 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// ForcePrimaryKey is kontext key to force read query executed in primary
+const ForcePrimaryKey = "db.force_primary"
+
+// ForcePrimary mark kontext so every read query using it is executed in primary, use it for read-after-write consistency
+func ForcePrimary(ktx kontext.Context) {
+	ktx.Set(ForcePrimaryKey, true)
+}
+
+// LoadBalancing strategy of choosing replica
+type LoadBalancing uint
+
+const (
+	// RoundRobin choose healthy replica one after another
+	RoundRobin LoadBalancing = iota
+	// LeastConnections choose healthy replica with the least in-flight query
+	LeastConnections
+)
+
+// WithLoadBalancing set replica load balancing strategy of cluster, default to RoundRobin
+func WithLoadBalancing(loadBalancing LoadBalancing) Option {
+	return func(c *Config) {
+		c.loadBalancing = loadBalancing
+	}
+}
+
+// WithReplicaCooldown set how long unhealthy replica is excluded from cluster routing, default to 5 seconds
+func WithReplicaCooldown(replicaCooldown time.Duration) Option {
+	return func(c *Config) {
+		c.replicaCooldown = replicaCooldown
+	}
+}
+
+type clusterReplica struct {
+	db             DB
+	inflight       int64
+	unhealthyUntil int64
+}
+
+// Cluster is DB implementation with one primary and several replicas.
+// Read query outside of transaction is routed into healthy replica, while write query and transaction is routed into primary.
+// Replica which return exception.Unavailable is marked as unhealthy until the cooldown passed, when there is no healthy replica primary is used instead.
+type Cluster struct {
+	primary  DB
+	replicas []*clusterReplica
+	config   Config
+	next     uint64
+}
+
+// AdaptCluster adapting primary and replicas into single DB
+func AdaptCluster(primary DB, replicas []DB, opts ...Option) *Cluster {
+	var config Config
+
+	// Default value
+	config.loadBalancing = RoundRobin
+	config.replicaCooldown = 5 * time.Second
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	cluster := &Cluster{primary: primary, config: config}
+	for _, replica := range replicas {
+		cluster.replicas = append(cluster.replicas, &clusterReplica{db: replica})
+	}
+
+	return cluster
+}
+
+// FabricateMySQLCluster will fabricate mysql primary and replicas connection and wrap it into single DB.
+// Replica instance is named with "<instanceName>-replica-<index>" starting from 0, options is applied to every instance.
+func FabricateMySQLCluster(instanceName string, primary Config, replicas []Config, opts ...Option) (*Cluster, exception.Exception) {
+	primaryDB, exc := FabricateMySQL(instanceName, primary, opts...)
+	if exc != nil {
+		return nil, exc
+	}
+
+	var replicaDBs []DB
+	for i, replica := range replicas {
+		replicaDB, exc := FabricateMySQL(fmt.Sprintf("%s-replica-%d", instanceName, i), replica, opts...)
+		if exc != nil {
+			return nil, exc
+		}
+
+		replicaDBs = append(replicaDBs, replicaDB)
+	}
+
+	return AdaptCluster(primaryDB, replicaDBs, opts...), nil
+}
+
+// Ping primary and every replica, replica which failed is marked as unhealthy. Only primary failure is returned.
+func (c *Cluster) Ping(ktx kontext.Context) exception.Exception {
+	var wg sync.WaitGroup
+	for _, replica := range c.replicas {
+		wg.Add(1)
+		go func(replica *clusterReplica) {
+			defer wg.Done()
+
+			if exc := replica.db.Ping(ktx); exc != nil {
+				c.markUnhealthy(replica)
+			} else {
+				atomic.StoreInt64(&replica.unhealthyUntil, 0)
+			}
+		}(replica)
+	}
+	wg.Wait()
+
+	return c.primary.Ping(ktx)
+}
+
+// Transaction is always executed in primary
+func (c *Cluster) Transaction(ktx kontext.Context, transactionKey string, f func(tx TX) exception.Exception, opts ...TransactionOption) exception.Exception {
+	return c.primary.Transaction(ktx, transactionKey, f, opts...)
+}
+
+// ExecContext is always executed in primary
+func (c *Cluster) ExecContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (Result, exception.Exception) {
+	return c.primary.ExecContext(ktx, queryKey, query, args...)
+}
+
+// QueryContext is executed in healthy replica, unless the kontext is forced into primary
+func (c *Cluster) QueryContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (Rows, exception.Exception) {
+	replica := c.replica(ktx)
+	if replica == nil {
+		return c.primary.QueryContext(ktx, queryKey, query, args...)
+	}
+
+	atomic.AddInt64(&replica.inflight, 1)
+	rows, exc := replica.db.QueryContext(ktx, queryKey, query, args...)
+	if exc != nil {
+		atomic.AddInt64(&replica.inflight, -1)
+
+		if exc.Type() == exception.Unavailable {
+			c.markUnhealthy(replica)
+			return c.primary.QueryContext(ktx, queryKey, query, args...)
+		}
+
+		return rows, exc
+	}
+
+	return &clusterRows{Rows: rows, done: c.doneFunc(replica)}, nil
+}
+
+// QueryRowContext is executed in healthy replica, unless the kontext is forced into primary.
+// Row is read lazily so replica unavailability is only known on Scan, the query is then re-run in primary the same as QueryContext.
+func (c *Cluster) QueryRowContext(ktx kontext.Context, queryKey, query string, args ...interface{}) Row {
+	replica := c.replica(ktx)
+	if replica == nil {
+		return c.primary.QueryRowContext(ktx, queryKey, query, args...)
+	}
+
+	atomic.AddInt64(&replica.inflight, 1)
+	return &clusterRow{
+		Row:  replica.db.QueryRowContext(ktx, queryKey, query, args...),
+		done: c.doneFunc(replica),
+		fallback: func() Row {
+			return c.primary.QueryRowContext(ktx, queryKey, query, args...)
+		},
+	}
+}
+
+// ExecNamed is always executed in primary
+func (c *Cluster) ExecNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (Result, exception.Exception) {
+	return c.primary.ExecNamed(ktx, queryKey, query, arg)
+}
+
+// QueryNamed is executed in healthy replica, unless the kontext is forced into primary
+func (c *Cluster) QueryNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (Rows, exception.Exception) {
+	query, args, exc := BindNamed(c.Dialect(), query, arg)
+	if exc != nil {
+		return adaptRows(nil, c.Dialect()), exc
+	}
+
+	return c.QueryContext(ktx, queryKey, query, args...)
+}
+
+// OnCommit is delegated into primary
+func (c *Cluster) OnCommit(ktx kontext.Context, f func(ktx kontext.Context)) {
+	c.primary.OnCommit(ktx, f)
+}
+
+// OnRollback is delegated into primary
+func (c *Cluster) OnRollback(f func(ktx kontext.Context)) {
+	c.primary.OnRollback(f)
+}
+
+// Dialect of primary
+func (c *Cluster) Dialect() Dialect {
+	return c.primary.Dialect()
+}
+
+// Lock is always acquired in primary
+func (c *Cluster) Lock(ktx kontext.Context, name string, timeout time.Duration) (Lock, exception.Exception) {
+	return c.primary.Lock(ktx, name, timeout)
+}
+
+// Stats of primary connection pool, use Replicas to get stats of each replica
+func (c *Cluster) Stats() PoolStats {
+	return c.primary.Stats()
+}
+
+// Eject primary sql.DB out of cluster
+func (c *Cluster) Eject() *sql.DB {
+	return c.primary.Eject()
+}
+
+// Primary return primary DB of the cluster
+func (c *Cluster) Primary() DB {
+	return c.primary
+}
+
+// Replicas return every replica DB of the cluster
+func (c *Cluster) Replicas() []DB {
+	replicas := make([]DB, 0, len(c.replicas))
+	for _, replica := range c.replicas {
+		replicas = append(replicas, replica.db)
+	}
+
+	return replicas
+}
+
+// replica choose healthy replica based on load balancing strategy, it return nil when primary should be used
+func (c *Cluster) replica(ktx kontext.Context) *clusterReplica {
+	if forced, ok := ktx.Get(ForcePrimaryKey); ok && forced == true {
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	healthy := func(replica *clusterReplica) bool {
+		return atomic.LoadInt64(&replica.unhealthyUntil) <= now
+	}
+
+	switch c.config.loadBalancing {
+	case LeastConnections:
+		var chosen *clusterReplica
+		for _, replica := range c.replicas {
+			if healthy(replica) && (chosen == nil || atomic.LoadInt64(&replica.inflight) < atomic.LoadInt64(&chosen.inflight)) {
+				chosen = replica
+			}
+		}
+
+		return chosen
+	default:
+		start := atomic.AddUint64(&c.next, 1) - 1
+		for i := range c.replicas {
+			replica := c.replicas[(start+uint64(i))%uint64(len(c.replicas))]
+			if healthy(replica) {
+				return replica
+			}
+		}
+
+		return nil
+	}
+}
+
+func (c *Cluster) markUnhealthy(replica *clusterReplica) {
+	atomic.StoreInt64(&replica.unhealthyUntil, time.Now().Add(c.config.replicaCooldown).UnixNano())
+}
+
+// doneFunc release in-flight counter of replica once and mark it unhealthy if the exception is unavailable
+func (c *Cluster) doneFunc(replica *clusterReplica) func(exc exception.Exception) {
+	var once sync.Once
+	return func(exc exception.Exception) {
+		once.Do(func() {
+			atomic.AddInt64(&replica.inflight, -1)
+		})
+
+		if exc != nil && exc.Type() == exception.Unavailable {
+			c.markUnhealthy(replica)
+		}
+	}
+}
+
+type clusterRows struct {
+	Rows
+	done func(exc exception.Exception)
+}
+
+// Next release in-flight counter as soon as the rows is exhausted instead of waiting for Close
+func (r *clusterRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.done(r.Rows.Err())
+	return false
+}
+
+func (r *clusterRows) Close() exception.Exception {
+	exc := r.Rows.Close()
+	r.done(exc)
+	return exc
+}
+
+type clusterRow struct {
+	Row
+	done     func(exc exception.Exception)
+	fallback func() Row
+}
+
+func (r *clusterRow) Scan(dest ...interface{}) exception.Exception {
+	exc := r.Row.Scan(dest...)
+	r.done(exc)
+
+	if exc != nil && exc.Type() == exception.Unavailable {
+		return r.fallback().Scan(dest...)
+	}
+
+	return exc
+}
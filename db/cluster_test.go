@@ -0,0 +1,242 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func fabricateClusterMock(t *testing.T, replicaCount int) ([]*sql.DB, []sqlmock.Sqlmock) {
+	var sqldbs []*sql.DB
+	var mocks []sqlmock.Sqlmock
+
+	for i := 0; i <= replicaCount; i++ {
+		sqldb, mockDB, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		sqldbs = append(sqldbs, sqldb)
+		mocks = append(mocks, mockDB)
+	}
+
+	return sqldbs, mocks
+}
+
+func TestCluster(t *testing.T) {
+	t.Run("When querying outside of transaction it will be routed into replica in round robin", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 2)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[1].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mocks[2].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mocks[1].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mocks[0].ExpectExec(`update users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mocks[0].ExpectBegin()
+		mocks[0].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+		mocks[0].ExpectCommit()
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1]), db.Adapt(sqldbs[2])})
+
+		for _, expected := range []int{1, 2, 1} {
+			var id int
+			assert.Nil(t, cluster.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id))
+			assert.Equal(t, expected, id)
+		}
+
+		_, exc := cluster.ExecContext(ktx, "update-user", "update users")
+		assert.Nil(t, exc)
+
+		exc = cluster.Transaction(ktx, "transaction-test", func(tx db.TX) exception.Exception {
+			var id int
+			return tx.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id)
+		})
+		assert.Nil(t, exc)
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("When kontext is forced into primary it will read from primary", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 1)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[0].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+
+		ktx := kontext.Fabricate()
+		db.ForcePrimary(ktx)
+
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1])})
+		rows, exc := cluster.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+		assert.Nil(t, rows.Close())
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("When replica unavailable it will fallback into primary and the replica is excluded until the cooldown passed", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 2)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[1].ExpectQuery(`select id from users`).WillReturnError(mysql.ErrInvalidConn)
+		mocks[0].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+		mocks[2].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mocks[2].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1]), db.Adapt(sqldbs[2])}, db.WithReplicaCooldown(time.Minute))
+
+		for i := 0; i < 3; i++ {
+			rows, exc := cluster.QueryContext(ktx, "list-users", "select id from users")
+			assert.Nil(t, exc)
+			assert.Nil(t, rows.Close())
+		}
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("When load balanced by least connections it will choose replica with the least in-flight query", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 2)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[1].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mocks[2].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mocks[1].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1]), db.Adapt(sqldbs[2])}, db.WithLoadBalancing(db.LeastConnections))
+
+		openRows, exc := cluster.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+
+		rows, exc := cluster.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+		assert.Nil(t, rows.Close())
+		assert.Nil(t, openRows.Close())
+
+		rows, exc = cluster.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+		assert.Nil(t, rows.Close())
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("When replica unavailable on query row it will fallback into primary on scan", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 1)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[1].ExpectQuery(`select id from users`).WillReturnError(mysql.ErrInvalidConn)
+		mocks[0].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+		mocks[0].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1])}, db.WithReplicaCooldown(time.Minute))
+
+		for i := 0; i < 2; i++ {
+			var id int
+			assert.Nil(t, cluster.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id))
+			assert.Equal(t, 0, id)
+		}
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("When rows is exhausted it will release in-flight query before it is closed", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 2)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[1].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mocks[1].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1]), db.Adapt(sqldbs[2])}, db.WithLoadBalancing(db.LeastConnections))
+
+		exhausted, exc := cluster.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+		for exhausted.Next() {
+		}
+
+		rows, exc := cluster.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+		assert.Nil(t, rows.Close())
+		assert.Nil(t, exhausted.Close())
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("When named query can not be bound it will return rows which can be closed", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 1)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1])})
+
+		rows, exc := cluster.QueryNamed(ktx, "find-user", "select id from users where id = :id", map[string]interface{}{})
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.False(t, rows.Next())
+		assert.Nil(t, rows.Close())
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		sqldbs, mocks := fabricateClusterMock(t, 1)
+		for _, sqldb := range sqldbs {
+			defer sqldb.Close()
+		}
+
+		mocks[0].ExpectPing()
+		mocks[1].ExpectPing().WillReturnError(errors.New("unexpected error"))
+		mocks[0].ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+
+		ktx := kontext.Fabricate()
+		cluster := db.AdaptCluster(db.Adapt(sqldbs[0]), []db.DB{db.Adapt(sqldbs[1])})
+		assert.Nil(t, cluster.Ping(ktx))
+		assert.Equal(t, sqldbs[0], cluster.Eject())
+		assert.Equal(t, 1, len(cluster.Replicas()))
+
+		var id int
+		assert.Nil(t, cluster.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id))
+		assert.Equal(t, 0, id)
+
+		for _, mockDB := range mocks {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}
+	})
+}
@@ -8,14 +8,50 @@ type Config struct {
 	Password string
 	Host     string
 
-	// If port is empty it will return 3306 instead
+	// If port is empty it will return default port of the dialect instead, 3306 for MySQL and 5432 for Postgres
 	Port string
 
 	Name string
 
+	tls          *TLSConfig
+	loc          *time.Location
+	dialTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	unixSocket   string
+	charset      string
+	collation    string
+	params       map[string]string
+
 	maxIdleConn     int
 	maxOpenConn     int
 	connMaxLifetime time.Duration
+
+	analyzers []Analyzer
+	dialect   Dialect
+	retry     *RetryConfig
+
+	statementCacheSize int
+	sqlComment         *SQLCommentConfig
+
+	instanceName                string
+	healthProbeInterval         time.Duration
+	healthProbeFailureThreshold int
+
+	loadBalancing   LoadBalancing
+	replicaCooldown time.Duration
+
+	lockReleaseTimeout time.Duration
+}
+
+// Params return copy of driver params set by WithParams, used by dialect of other package to build its DSN
+func (c Config) Params() map[string]string {
+	params := make(map[string]string, len(c.params))
+	for key, value := range c.params {
+		params[key] = value
+	}
+
+	return params
 }
 
 // Option when fabricating connection
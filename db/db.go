@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/kodefluence/monorepo/exception"
 	"github.com/kodefluence/monorepo/kontext"
@@ -14,19 +15,27 @@ type DB interface {
 	Ping(ktx kontext.Context) exception.Exception
 	Transactionable
 	TX
+	Lock(ktx kontext.Context, name string, timeout time.Duration) (Lock, exception.Exception)
+	Stats() PoolStats
 	Eject() *sql.DB
 }
 
 // Transactionable is wrapper to create transaction process
 type Transactionable interface {
-	Transaction(ctx kontext.Context, transactionKey string, f func(tx TX) exception.Exception) exception.Exception
+	Transaction(ctx kontext.Context, transactionKey string, f func(tx TX) exception.Exception, opts ...TransactionOption) exception.Exception
 }
 
-// TX is database transaction
+// TX is database transaction, calling Transaction from TX will create nested transaction using savepoint
 type TX interface {
+	Transactionable
 	ExecContext(ctx kontext.Context, queryKey, query string, args ...interface{}) (Result, exception.Exception)
 	QueryContext(ctx kontext.Context, queryKey, query string, args ...interface{}) (Rows, exception.Exception)
 	QueryRowContext(ctx kontext.Context, queryKey, query string, args ...interface{}) Row
+	ExecNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (Result, exception.Exception)
+	QueryNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (Rows, exception.Exception)
+	OnCommit(ctx kontext.Context, f func(ktx kontext.Context))
+	OnRollback(f func(ktx kontext.Context))
+	Dialect() Dialect
 }
 
 // Result summarizes an executed SQL command.
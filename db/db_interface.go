@@ -14,17 +14,24 @@ import (
 
 var instanceList = &sync.Map{}
 
-// FabricateMySQL will fabricate mysql connection and wrap it into SQL interfaces
-func FabricateMySQL(instanceName string, config Config, opts ...Option) (DB, exception.Exception) {
-	if val, ok := instanceList.Load(fmt.Sprintf("mysql-%s", instanceName)); ok {
-		return Adapt(val.(*sql.DB)), nil
-	}
+// instance fabricated by Fabricate
+type instance struct {
+	db      *sql.DB
+	name    string
+	dialect Dialect
+	probe   *healthProbe
+}
 
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&interpolateParams=true", config.Username, config.Password, config.Host, config.Port, config.Name))
-	if err != nil {
-		return nil, exception.Throw(err)
+// Fabricate will fabricate database connection of given dialect and wrap it into SQL interfaces
+func Fabricate(instanceName string, dialect Dialect, config Config, opts ...Option) (DB, exception.Exception) {
+	opts = append([]Option{WithDialect(dialect), withInstanceName(instanceName)}, opts...)
+
+	if val, ok := instanceList.Load(instanceKey(dialect, instanceName)); ok {
+		return Adapt(val.(*instance).db, opts...), nil
 	}
 
+	registerDialect(dialect)
+
 	// Default value
 	config.maxIdleConn = 2
 	config.maxOpenConn = 0
@@ -33,31 +40,58 @@ func FabricateMySQL(instanceName string, config Config, opts ...Option) (DB, exc
 		opt(&config)
 	}
 
+	dsn, exc := dialect.DSN(config)
+	if exc != nil {
+		return nil, exc
+	}
+
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		return nil, exception.Throw(err)
+	}
+
 	db.SetConnMaxLifetime(config.connMaxLifetime)
 	db.SetMaxIdleConns(config.maxIdleConn)
 	db.SetMaxOpenConns(config.maxOpenConn)
 
-	instanceList.Store(fmt.Sprintf("mysql-%s", instanceName), db)
+	fabricated := &instance{db: db, name: instanceName, dialect: dialect}
+	if config.healthProbeInterval > 0 {
+		fabricated.probe = startHealthProbe(db, dialect, config.healthProbeInterval, config.healthProbeFailureThreshold)
+	}
+
+	instanceList.Store(instanceKey(dialect, instanceName), fabricated)
 
-	return Adapt(db), nil
+	return Adapt(db, opts...), nil
+}
+
+// FabricateMySQL will fabricate mysql connection and wrap it into SQL interfaces
+func FabricateMySQL(instanceName string, config Config, opts ...Option) (DB, exception.Exception) {
+	return Fabricate(instanceName, MySQL, config, opts...)
 }
 
-// GetInstance that already fabricated before as an sql.DB
+// GetInstance that already fabricated before as an sql.DB. If the same instance name fabricated by several dialects, MySQL instance is returned first.
 func GetInstance(instanceName string) (*sql.DB, exception.Exception) {
-	if val, ok := instanceList.Load(fmt.Sprintf("mysql-%s", instanceName)); ok {
-		return val.(*sql.DB), nil
+	for _, dialect := range knownDialects() {
+		if val, ok := instanceList.Load(instanceKey(dialect, instanceName)); ok {
+			return val.(*instance).db, nil
+		}
 	}
 
 	return nil, exception.Throw(errors.New("unexpected error"), exception.WithType(exception.NotFound))
 }
 
-// CloseAll initiated mysql connection
+// CloseAll initiated database connection and stop its health probe
 func CloseAll() []exception.Exception {
 	var excs []exception.Exception
 
 	instanceList.Range(func(key, value interface{}) bool {
-		if err := value.(*sql.DB).Close(); err != nil {
-			excs = append(excs, exception.Throw(err, exception.WithTitle("error closing mysql connection"), exception.WithDetail(fmt.Sprintf("instance name: %s", key.(string)))))
+		fabricated := value.(*instance)
+		if fabricated.probe != nil {
+			fabricated.probe.stop()
+		}
+
+		if err := fabricated.db.Close(); err != nil {
+			excs = append(excs, exception.Throw(err, exception.WithTitle("error closing database connection"), exception.WithDetail(fmt.Sprintf("instance name: %s", key.(string)))))
 		}
 
 		return true
@@ -65,3 +99,7 @@ func CloseAll() []exception.Exception {
 
 	return excs
 }
+
+func instanceKey(dialect Dialect, instanceName string) string {
+	return fmt.Sprintf("%s-%s", dialect.Name(), instanceName)
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Dialect describe database specific behaviour so the same DB interface can be used across database
+type Dialect interface {
+	// Name of the dialect, also used as prefix of fabricated instance name
+	Name() string
+
+	// DriverName registered in database/sql
+	DriverName() string
+
+	// DSN build data source name from config, invalid config is returned as exception.BadInput
+	DSN(config Config) (string, exception.Exception)
+
+	// Placeholder return bind variable of given position, position is started from 1
+	Placeholder(position int) string
+
+	// Translate driver error into exception with appropriate exception type
+	Translate(err error) exception.Exception
+
+	// StaleStatement report whether err mean prepared statement can not be reused, such as broken connection or dropped schema object
+	StaleStatement(err error) bool
+
+	// Retryable report whether err mean the transaction is aborted by the database and can be re-run, such as deadlock or lock wait timeout
+	Retryable(err error) bool
+
+	// MaxPlaceholders of single statement, used by BulkInsert to split rows into several statements
+	MaxPlaceholders() int
+
+	// Upsert return clause appended into insert statement updating updateColumns with the inserted value when the row conflict.
+	// Dialect requiring conflict target return exception.BadInput when conflictColumns is empty, dialect which does not need it ignore it.
+	Upsert(conflictColumns, updateColumns []string) (string, exception.Exception)
+
+	// Returning report whether insert statement support RETURNING clause to read generated column
+	Returning() bool
+
+	// CreateTable return statements creating table and its indexes when they do not exist yet
+	CreateTable(table Table) []string
+}
+
+var (
+	// MySQL dialect, using github.com/go-sql-driver/mysql
+	MySQL Dialect = mysqlDialect{}
+
+	// Postgres dialect, the driver is not imported by this package so only service connecting to postgres link it, see package db/postgres
+	Postgres Dialect = postgresDialect{}
+)
+
+var (
+	dialectMutex sync.Mutex
+
+	// dialects known by GetInstance, ordered by lookup priority. Dialect of other package such as db/sqlite is added when it is fabricated.
+	dialects = []Dialect{MySQL, Postgres}
+)
+
+// knownDialects return copy of dialects so it can be iterated without holding the lock
+func knownDialects() []Dialect {
+	dialectMutex.Lock()
+	defer dialectMutex.Unlock()
+
+	return append([]Dialect{}, dialects...)
+}
+
+// registerDialect add dialect into dialects when there is no dialect with the same name
+func registerDialect(dialect Dialect) {
+	dialectMutex.Lock()
+	defer dialectMutex.Unlock()
+
+	for _, known := range dialects {
+		if known.Name() == dialect.Name() {
+			return
+		}
+	}
+
+	dialects = append(dialects, dialect)
+}
+
+// WithDialect adapt connection with specific dialect, default to MySQL
+func WithDialect(dialect Dialect) Option {
+	return func(c *Config) {
+		c.dialect = dialect
+	}
+}
+
+// Rebind rewrite every "?" bind variable in query into dialect placeholder.
+// Question mark inside quoted string or identifier is left as it is.
+func Rebind(dialect Dialect, query string) string {
+	if dialect.Placeholder(1) == "?" {
+		return query
+	}
+
+	var builder strings.Builder
+	var quote rune
+	position := 0
+
+	for _, char := range query {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '?':
+			position++
+			builder.WriteString(dialect.Placeholder(position))
+			continue
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
+
+func translate(dialect Dialect, err error) exception.Exception {
+	if dialect == nil {
+		dialect = MySQL
+	}
+
+	return dialect.Translate(err)
+}
+
+// BadConnection report whether err mean the connection is broken, used by dialect implementing StaleStatement
+func BadConnection(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
+
+// TranslateGeneric translate error which is not specific to any driver such as sql.ErrNoRows and broken connection,
+// used by dialect Translate when err is not its driver error
+func TranslateGeneric(err error) exception.Exception {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return exception.Throw(err, exception.WithType(exception.NotFound))
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		return exception.Throw(err, exception.WithType(exception.Unavailable), exception.WithTitle("database unavailable"))
+	}
+
+	return exception.Throw(err)
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/kodefluence/monorepo/exception"
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) DriverName() string {
+	return "mysql"
+}
+
+// DSN of mysql is built using driver config with parseTime and interpolateParams enabled
+func (mysqlDialect) DSN(config Config) (string, exception.Exception) {
+	if exc := validateDSNConfig(config); exc != nil {
+		return "", exc
+	}
+
+	port := config.Port
+	if port == "" {
+		port = "3306"
+	}
+
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = config.Username
+	mysqlConfig.Passwd = config.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = net.JoinHostPort(config.Host, port)
+	mysqlConfig.DBName = config.Name
+	mysqlConfig.ParseTime = true
+	mysqlConfig.InterpolateParams = true
+	mysqlConfig.Timeout = config.dialTimeout
+	mysqlConfig.ReadTimeout = config.readTimeout
+	mysqlConfig.WriteTimeout = config.writeTimeout
+
+	if config.unixSocket != "" {
+		mysqlConfig.Net = "unix"
+		mysqlConfig.Addr = config.unixSocket
+	}
+
+	if config.loc != nil {
+		mysqlConfig.Loc = config.loc
+	}
+
+	if config.collation != "" {
+		mysqlConfig.Collation = config.collation
+	}
+
+	if config.charset != "" || len(config.params) > 0 {
+		mysqlConfig.Params = map[string]string{}
+	}
+
+	if config.charset != "" {
+		mysqlConfig.Params["charset"] = config.charset
+	}
+
+	for key, value := range config.params {
+		mysqlConfig.Params[key] = value
+	}
+
+	if config.tls != nil {
+		tlsConfig, exc := loadTLSConfig(config)
+		if exc != nil {
+			return "", exc
+		}
+
+		mysqlConfig.TLSConfig = config.tls.name(config.Host)
+		if err := mysql.RegisterTLSConfig(mysqlConfig.TLSConfig, tlsConfig); err != nil {
+			return "", exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+		}
+	}
+
+	return mysqlConfig.FormatDSN(), nil
+}
+
+func (mysqlDialect) Placeholder(position int) string {
+	return "?"
+}
+
+// Translate mysql error based on server error number, see https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+func (mysqlDialect) Translate(err error) exception.Exception {
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return exception.Throw(err, exception.WithType(exception.Unavailable), exception.WithTitle("database unavailable"))
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return TranslateGeneric(err)
+	}
+
+	detail := exception.WithDetail(fmt.Sprintf("mysql error code: %d", mysqlErr.Number))
+
+	switch mysqlErr.Number {
+	case 1062, // ER_DUP_ENTRY
+		1586: // ER_DUP_ENTRY_WITH_KEY_NAME
+		return exception.Throw(err, exception.WithType(exception.Duplicated), exception.WithTitle("duplicated data"), detail)
+	case 1048, // ER_BAD_NULL_ERROR
+		1216, // ER_NO_REFERENCED_ROW
+		1217, // ER_ROW_IS_REFERENCED
+		1264, // ER_WARN_DATA_OUT_OF_RANGE
+		1292, // ER_TRUNCATED_WRONG_VALUE
+		1364, // ER_NO_DEFAULT_FOR_FIELD
+		1366, // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+		1406, // ER_DATA_TOO_LONG
+		1451, // ER_ROW_IS_REFERENCED_2
+		1452, // ER_NO_REFERENCED_ROW_2
+		3819: // ER_CHECK_CONSTRAINT_VIOLATED
+		return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid data"), detail)
+	case 1205, // ER_LOCK_WAIT_TIMEOUT
+		1213: // ER_LOCK_DEADLOCK
+		return exception.Throw(err, exception.WithType(exception.Conflict), exception.WithTitle("transaction conflict"), detail)
+	case 1040, // ER_CON_COUNT_ERROR
+		1053: // ER_SERVER_SHUTDOWN
+		return exception.Throw(err, exception.WithType(exception.Unavailable), exception.WithTitle("database unavailable"), detail)
+	}
+
+	return exception.Throw(err, detail)
+}
+
+// Retryable on ER_LOCK_DEADLOCK and ER_LOCK_WAIT_TIMEOUT
+func (mysqlDialect) Retryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	return mysqlErr.Number == 1205 || mysqlErr.Number == 1213
+}
+
+// MaxPlaceholders of mysql prepared statement
+func (mysqlDialect) MaxPlaceholders() int {
+	return 65535
+}
+
+// Upsert using ON DUPLICATE KEY UPDATE which conflict on any unique key, conflictColumns is ignored
+func (mysqlDialect) Upsert(conflictColumns, updateColumns []string) (string, exception.Exception) {
+	assignments := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		assignments = append(assignments, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", "), nil
+}
+
+// Returning is not supported by mysql, generated key is read using LastInsertId
+func (mysqlDialect) Returning() bool {
+	return false
+}
+
+// CreateTable with indexes declared inline since mysql does not support CREATE INDEX IF NOT EXISTS
+func (mysqlDialect) CreateTable(table Table) []string {
+	var definitions []string
+	for _, column := range table.Columns {
+		definitions = append(definitions, column.Definition(mysqlColumnTypes[column.Type]))
+	}
+
+	for _, index := range table.Indexes {
+		definitions = append(definitions, fmt.Sprintf("INDEX %s (%s)", index.Name, strings.Join(index.Columns, ", ")))
+	}
+
+	return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table.Name, strings.Join(definitions, ", "))}
+}
+
+var mysqlColumnTypes = map[ColumnType]string{
+	AutoIncrement: "BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY",
+	String:        "VARCHAR(255)",
+	Text:          "TEXT",
+	Bytes:         "LONGBLOB",
+	Integer:       "INT",
+	Timestamp:     "DATETIME(6)",
+}
+
+// StaleStatement on broken connection, ER_NEED_REPREPARE or when table or column of the statement is dropped
+func (mysqlDialect) StaleStatement(err error) bool {
+	if BadConnection(err) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case 1054, // ER_BAD_FIELD_ERROR
+		1146, // ER_NO_SUCH_TABLE
+		1615: // ER_NEED_REPREPARE
+		return true
+	}
+
+	return false
+}
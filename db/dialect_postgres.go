@@ -0,0 +1,220 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) DriverName() string {
+	return "postgres"
+}
+
+// DSN of postgres, TLS files is passed into sslrootcert, sslcert and sslkey. Read timeout, write timeout and collation is ignored
+func (postgresDialect) DSN(config Config) (string, exception.Exception) {
+	if exc := validateDSNConfig(config); exc != nil {
+		return "", exc
+	}
+
+	port := config.Port
+	if port == "" {
+		port = "5432"
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", "disable")
+
+	if config.tls != nil {
+		switch {
+		case config.tls.InsecureSkipVerify:
+			query.Set("sslmode", "require")
+		case config.tls.CAFile != "":
+			query.Set("sslmode", "verify-full")
+			query.Set("sslrootcert", config.tls.CAFile)
+		default:
+			query.Set("sslmode", "verify-full")
+		}
+
+		if config.tls.CertFile != "" {
+			query.Set("sslcert", config.tls.CertFile)
+			query.Set("sslkey", config.tls.KeyFile)
+		}
+	}
+
+	if config.dialTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(math.Ceil(config.dialTimeout.Seconds()))))
+	}
+
+	if config.loc != nil {
+		query.Set("timezone", config.loc.String())
+	}
+
+	if config.charset != "" {
+		query.Set("client_encoding", config.charset)
+	}
+
+	if config.unixSocket != "" {
+		query.Set("host", config.unixSocket)
+	}
+
+	for key, value := range config.params {
+		query.Set(key, value)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     net.JoinHostPort(config.Host, port),
+		Path:     "/" + config.Name,
+		RawQuery: query.Encode(),
+	}
+
+	return dsn.String(), nil
+}
+
+func (postgresDialect) Placeholder(position int) string {
+	return "$" + strconv.Itoa(position)
+}
+
+// Translate postgres error based on SQLSTATE, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+func (postgresDialect) Translate(err error) exception.Exception {
+	code, ok := sqlState(err)
+	if !ok {
+		return TranslateGeneric(err)
+	}
+
+	detail := exception.WithDetail(fmt.Sprintf("postgres error code: %s", code))
+
+	switch code {
+	case "23505": // unique_violation
+		return exception.Throw(err, exception.WithType(exception.Duplicated), exception.WithTitle("duplicated data"), detail)
+	case "23502", // not_null_violation
+		"23503", // foreign_key_violation
+		"23514", // check_violation
+		"22001", // string_data_right_truncation
+		"22003", // numeric_value_out_of_range
+		"22007", // invalid_datetime_format
+		"22P02": // invalid_text_representation
+		return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid data"), detail)
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"55P03": // lock_not_available
+		return exception.Throw(err, exception.WithType(exception.Conflict), exception.WithTitle("transaction conflict"), detail)
+	}
+
+	// Class 08 is connection exception and class 57 is operator intervention
+	if class := code[:2]; class == "08" || class == "57" {
+		return exception.Throw(err, exception.WithType(exception.Unavailable), exception.WithTitle("database unavailable"), detail)
+	}
+
+	return exception.Throw(err, detail)
+}
+
+// Retryable on serialization failure, deadlock and lock not available
+func (postgresDialect) Retryable(err error) bool {
+	code, ok := sqlState(err)
+	if !ok {
+		return false
+	}
+
+	switch code {
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"55P03": // lock_not_available
+		return true
+	}
+
+	return false
+}
+
+// MaxPlaceholders of postgres bind message
+func (postgresDialect) MaxPlaceholders() int {
+	return 65535
+}
+
+// Upsert using ON CONFLICT DO UPDATE which require conflictColumns as the conflict target
+func (postgresDialect) Upsert(conflictColumns, updateColumns []string) (string, exception.Exception) {
+	if len(conflictColumns) == 0 {
+		return "", exception.Throw(fmt.Errorf("upsert require conflict columns"), exception.WithType(exception.BadInput))
+	}
+
+	assignments := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflictColumns, ", "), strings.Join(assignments, ", ")), nil
+}
+
+// Returning is supported by postgres
+func (postgresDialect) Returning() bool {
+	return true
+}
+
+// CreateTable followed by CREATE INDEX IF NOT EXISTS of each index
+func (postgresDialect) CreateTable(table Table) []string {
+	definitions := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		definitions = append(definitions, column.Definition(postgresColumnTypes[column.Type]))
+	}
+
+	statements := []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table.Name, strings.Join(definitions, ", "))}
+	for _, index := range table.Indexes {
+		statements = append(statements, index.Create(table.Name))
+	}
+
+	return statements
+}
+
+var postgresColumnTypes = map[ColumnType]string{
+	AutoIncrement: "BIGSERIAL PRIMARY KEY",
+	String:        "VARCHAR(255)",
+	Text:          "TEXT",
+	Bytes:         "BYTEA",
+	Integer:       "INTEGER",
+	Timestamp:     "TIMESTAMPTZ",
+}
+
+// StaleStatement on broken connection, changed result type of cached plan or when table or column of the statement is dropped
+func (postgresDialect) StaleStatement(err error) bool {
+	if BadConnection(err) {
+		return true
+	}
+
+	code, ok := sqlState(err)
+	if !ok {
+		return false
+	}
+
+	switch code {
+	case "0A000", // feature_not_supported, returned as "cached plan must not change result type"
+		"26000", // invalid_sql_statement_name
+		"42P01", // undefined_table
+		"42703": // undefined_column
+		return true
+	}
+
+	return code[:2] == "08"
+}
+
+// sqlState return SQLSTATE of postgres driver error, both github.com/lib/pq and github.com/jackc/pgx error expose it
+func sqlState(err error) (string, bool) {
+	var stateErr interface{ SQLState() string }
+	if !errors.As(err, &stateErr) || len(stateErr.SQLState()) != 5 {
+		return "", false
+	}
+
+	return stateErr.SQLState(), true
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// TLSConfig of encrypted database connection, files is PEM encoded
+type TLSConfig struct {
+	// CAFile verify server certificate using given certificate authority instead of system pool
+	CAFile string
+
+	// CertFile and KeyFile is client certificate, both must be set together
+	CertFile string
+	KeyFile  string
+
+	// ServerName used to verify server certificate, default to config host
+	ServerName string
+
+	InsecureSkipVerify bool
+}
+
+// WithTLS connect using TLS
+func WithTLS(tlsConfig TLSConfig) Option {
+	return func(c *Config) {
+		c.tls = &tlsConfig
+	}
+}
+
+// WithLocation set time zone used to parse and send time value, default to UTC
+func WithLocation(loc *time.Location) Option {
+	return func(c *Config) {
+		c.loc = loc
+	}
+}
+
+// WithDialTimeout set timeout of establishing connection
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.dialTimeout = timeout
+	}
+}
+
+// WithReadTimeout set I/O read timeout, it is only supported by MySQL
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout set I/O write timeout, it is only supported by MySQL
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.writeTimeout = timeout
+	}
+}
+
+// WithUnixSocket connect through unix socket instead of host and port. For postgres it is the directory containing the socket
+func WithUnixSocket(path string) Option {
+	return func(c *Config) {
+		c.unixSocket = path
+	}
+}
+
+// WithCharset set connection character set
+func WithCharset(charset string) Option {
+	return func(c *Config) {
+		c.charset = charset
+	}
+}
+
+// WithCollation set connection collation, it is only supported by MySQL
+func WithCollation(collation string) Option {
+	return func(c *Config) {
+		c.collation = collation
+	}
+}
+
+// WithParams add arbitrary driver parameters into DSN, it can be called multiple times and the later value win
+func WithParams(params map[string]string) Option {
+	return func(c *Config) {
+		if c.params == nil {
+			c.params = map[string]string{}
+		}
+
+		for key, value := range params {
+			c.params[key] = value
+		}
+	}
+}
+
+// DSN build data source name of dialect from config and options the same way as Fabricate does, e.g. to be used by external tools
+func DSN(dialect Dialect, config Config, opts ...Option) (string, exception.Exception) {
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return dialect.DSN(config)
+}
+
+// validateDSNConfig validate options shared by every dialect
+func validateDSNConfig(config Config) exception.Exception {
+	for name, timeout := range map[string]time.Duration{"dial": config.dialTimeout, "read": config.readTimeout, "write": config.writeTimeout} {
+		if timeout < 0 {
+			return exception.Throw(fmt.Errorf("%s timeout must not be negative, got %s", name, timeout), exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+		}
+	}
+
+	if config.tls == nil {
+		return nil
+	}
+
+	if (config.tls.CertFile == "") != (config.tls.KeyFile == "") {
+		return exception.Throw(fmt.Errorf("tls cert file and key file must be set together"), exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+	}
+
+	for _, file := range []string{config.tls.CAFile, config.tls.CertFile, config.tls.KeyFile} {
+		if file == "" {
+			continue
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+		}
+	}
+
+	return nil
+}
+
+// loadTLSConfig load certificate files into tls.Config
+func loadTLSConfig(config Config) (*tls.Config, exception.Exception) {
+	tlsConfig := &tls.Config{ServerName: config.tls.ServerName, InsecureSkipVerify: config.tls.InsecureSkipVerify}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = config.Host
+	}
+
+	if config.tls.CAFile != "" {
+		ca, err := os.ReadFile(config.tls.CAFile)
+		if err != nil {
+			return nil, exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, exception.Throw(fmt.Errorf("no certificate found in tls ca file %s", config.tls.CAFile), exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+		}
+	}
+
+	if config.tls.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.tls.CertFile, config.tls.KeyFile)
+		if err != nil {
+			return nil, exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid database config"))
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// name is stable name of tls config registered into driver, so registering the same config twice does not create new entry
+func (t TLSConfig) name(host string) string {
+	return fmt.Sprintf("monorepo-%x", sha1.Sum([]byte(fmt.Sprintf("%s|%s|%s|%s|%s|%t", host, t.CAFile, t.CertFile, t.KeyFile, t.ServerName, t.InsecureSkipVerify))))
+}
@@ -0,0 +1,319 @@
+package fake
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Query executed against the fake
+type Query struct {
+	// ExecutionLevel is "db" or "tx"
+	ExecutionLevel string
+	Function       string
+	TransactionKey string
+	Key            string
+	SQL            string
+	Args           []interface{}
+}
+
+// Transaction finished by the fake
+type Transaction struct {
+	Key        string
+	Nested     bool
+	Committed  bool
+	RolledBack bool
+	Exception  exception.Exception
+}
+
+// Config of fake database
+type Config struct {
+	dialect db.Dialect
+	strict  bool
+	loose   bool
+}
+
+// Option of fake database
+type Option func(*Config)
+
+// WithDialect set dialect of fake database, default to db.MySQL
+func WithDialect(dialect db.Dialect) Option {
+	return func(c *Config) {
+		c.dialect = dialect
+	}
+}
+
+// WithStrict return exception when executed query key has no scripted response, by default exec return zero result and query return empty rows
+func WithStrict() Option {
+	return func(c *Config) {
+		c.strict = true
+	}
+}
+
+// WithLooseMatching make Replay answer query by its query key only, SQL and args is not compared with the recording
+func WithLooseMatching() Option {
+	return func(c *Config) {
+		c.loose = true
+	}
+}
+
+// DB is in-memory fake of db.DB, query is answered by scripted response of its query key and every query and transaction is recorded
+type DB struct {
+	config       Config
+	mutex        sync.Mutex
+	responses    map[string][]*Response
+	queries      []Query
+	transactions []Transaction
+	pingExc      exception.Exception
+	locks        map[string]chan struct{}
+}
+
+// Fabricate fake database
+func Fabricate(opts ...Option) *DB {
+	var config Config
+
+	// Default value
+	config.dialect = db.MySQL
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &DB{config: config, responses: map[string][]*Response{}, locks: map[string]chan struct{}{}}
+}
+
+// On script response of query key. Calling On several times for the same key queue the responses in order and the last response is repeated.
+func (f *DB) On(queryKey string) *Response {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	response := &Response{}
+	f.responses[queryKey] = append(f.responses[queryKey], response)
+
+	return response
+}
+
+// FailPing make Ping return exc
+func (f *DB) FailPing(exc exception.Exception) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.pingExc = exc
+}
+
+// Queries executed against the fake in order, filtered by query keys when given
+func (f *DB) Queries(queryKeys ...string) []Query {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var queries []Query
+	for _, query := range f.queries {
+		if len(queryKeys) == 0 || contains(queryKeys, query.Key) {
+			queries = append(queries, query)
+		}
+	}
+
+	return queries
+}
+
+// Executed return true when query key has been executed at least once
+func (f *DB) Executed(queryKey string) bool {
+	return len(f.Queries(queryKey)) > 0
+}
+
+// Transactions finished by the fake in order, filtered by transaction keys when given
+func (f *DB) Transactions(transactionKeys ...string) []Transaction {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var transactions []Transaction
+	for _, transaction := range f.transactions {
+		if len(transactionKeys) == 0 || contains(transactionKeys, transaction.Key) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions
+}
+
+// Committed return true when the latest transaction of the key is committed
+func (f *DB) Committed(transactionKey string) bool {
+	transactions := f.Transactions(transactionKey)
+	return len(transactions) > 0 && transactions[len(transactions)-1].Committed
+}
+
+// RolledBack return true when the latest transaction of the key is rolled back
+func (f *DB) RolledBack(transactionKey string) bool {
+	transactions := f.Transactions(transactionKey)
+	return len(transactions) > 0 && transactions[len(transactions)-1].RolledBack
+}
+
+// Reset forget every scripted response and recorded query and transaction
+func (f *DB) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.responses = map[string][]*Response{}
+	f.queries = nil
+	f.transactions = nil
+	f.pingExc = nil
+}
+
+// Ping return exception set by FailPing
+func (f *DB) Ping(ktx kontext.Context) exception.Exception {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.pingExc
+}
+
+// Transaction run f and record whether it is committed or rolled back, the transaction is rolled back when f return exception
+func (f *DB) Transaction(ktx kontext.Context, transactionKey string, fn func(tx db.TX) exception.Exception, opts ...db.TransactionOption) exception.Exception {
+	tx := &TX{db: f, transactionKey: transactionKey}
+	return tx.run(ktx, false, fn)
+}
+
+// ExecContext answer with scripted result of query key
+func (f *DB) ExecContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (db.Result, exception.Exception) {
+	return f.exec(Query{ExecutionLevel: "db", Function: "ExecContext", Key: queryKey, SQL: query, Args: args})
+}
+
+// QueryContext answer with scripted rows of query key
+func (f *DB) QueryContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (db.Rows, exception.Exception) {
+	return f.query(Query{ExecutionLevel: "db", Function: "QueryContext", Key: queryKey, SQL: query, Args: args})
+}
+
+// QueryRowContext answer with the first scripted row of query key
+func (f *DB) QueryRowContext(ktx kontext.Context, queryKey, query string, args ...interface{}) db.Row {
+	return f.queryRow(Query{ExecutionLevel: "db", Function: "QueryRowContext", Key: queryKey, SQL: query, Args: args})
+}
+
+// ExecNamed bind named parameters using db.BindNamed and answer with scripted result of query key
+func (f *DB) ExecNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (db.Result, exception.Exception) {
+	query, args, exc := db.BindNamed(f.config.dialect, query, arg)
+	if exc != nil {
+		return &result{}, exc
+	}
+
+	return f.exec(Query{ExecutionLevel: "db", Function: "ExecContext", Key: queryKey, SQL: query, Args: args})
+}
+
+// QueryNamed bind named parameters using db.BindNamed and answer with scripted rows of query key
+func (f *DB) QueryNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (db.Rows, exception.Exception) {
+	query, args, exc := db.BindNamed(f.config.dialect, query, arg)
+	if exc != nil {
+		return &rows{}, exc
+	}
+
+	return f.query(Query{ExecutionLevel: "db", Function: "QueryContext", Key: queryKey, SQL: query, Args: args})
+}
+
+// OnCommit execute f immediately with ktx since query outside of transaction is committed right away
+func (f *DB) OnCommit(ktx kontext.Context, fn func(ktx kontext.Context)) {
+	fn(ktx)
+}
+
+// OnRollback is never executed outside of transaction
+func (f *DB) OnRollback(fn func(ktx kontext.Context)) {}
+
+// Dialect of fake database
+func (f *DB) Dialect() db.Dialect {
+	return f.config.dialect
+}
+
+// Stats of fake database is always empty
+func (f *DB) Stats() db.PoolStats {
+	return db.PoolStats{InstanceName: "fake", Dialect: f.config.dialect.Name()}
+}
+
+// Eject return nil since there is no real connection
+func (f *DB) Eject() *sql.DB {
+	return nil
+}
+
+func (f *DB) record(query Query) (*Response, exception.Exception) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.queries = append(f.queries, query)
+
+	responses := f.responses[query.Key]
+	if len(responses) == 0 {
+		if f.config.strict {
+			return nil, exception.Throw(fmt.Errorf("no response scripted for query key %s", query.Key), exception.WithTitle("unexpected query"))
+		}
+
+		return &Response{}, nil
+	}
+
+	// Mismatched query does not consume the response so the following query is still answered in order
+	if exc := responses[0].match(query); exc != nil {
+		return nil, exc
+	}
+
+	if len(responses) > 1 {
+		f.responses[query.Key] = responses[1:]
+	}
+
+	return responses[0], nil
+}
+
+func (f *DB) exec(query Query) (db.Result, exception.Exception) {
+	response, exc := f.record(query)
+	if exc != nil {
+		return &result{}, exc
+	}
+
+	return &result{lastInsertID: response.lastInsertID, rowsAffected: response.rowsAffected}, response.exc
+}
+
+func (f *DB) query(query Query) (db.Rows, exception.Exception) {
+	response, exc := f.record(query)
+	if exc != nil {
+		return &rows{}, exc
+	}
+
+	if response.exc != nil {
+		return &rows{}, response.exc
+	}
+
+	return &rows{columns: response.columns, values: response.rows, index: -1}, nil
+}
+
+func (f *DB) queryRow(query Query) db.Row {
+	response, exc := f.record(query)
+	if exc != nil {
+		return &row{exc: exc}
+	}
+
+	if response.exc != nil {
+		return &row{exc: response.exc}
+	}
+
+	if len(response.rows) == 0 {
+		return &row{exc: exception.Throw(sql.ErrNoRows, exception.WithType(exception.NotFound))}
+	}
+
+	return &row{values: response.rows[0]}
+}
+
+func (f *DB) finish(transaction Transaction) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.transactions = append(f.transactions, transaction)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+package fake_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/fake"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID       int64   `db:"id"`
+	Name     string  `db:"name"`
+	Nickname *string `db:"nickname"`
+}
+
+// userRepository is an example of code under test
+type userRepository struct {
+	db db.DB
+}
+
+func (r *userRepository) rename(ktx kontext.Context, id int64, name string) exception.Exception {
+	return r.db.Transaction(ktx, "rename-user", func(tx db.TX) exception.Exception {
+		if exc := tx.QueryRowContext(ktx, "users.find_by_id", "select id, name, nickname from users where id = ?", id).Scan(new(int64), new(string), new(*string)); exc != nil {
+			return exc
+		}
+
+		_, exc := tx.ExecNamed(ktx, "users.rename", "update users set name = :name where id = :id", map[string]interface{}{"id": id, "name": name})
+		return exc
+	})
+}
+
+func TestFake(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When query key is scripted with rows it will return the rows", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		fakeDB.On("users.list").ReturnRows([]string{"id", "name", "nickname"}, []interface{}{1, "john", "johnny"}, []interface{}{int32(2), "jane", nil})
+
+		rows, exc := fakeDB.QueryContext(ktx, "users.list", "select id, name, nickname from users")
+		assert.Nil(t, exc)
+
+		users, exc := db.ScanAll[user](rows)
+		assert.Nil(t, exc)
+		assert.Equal(t, 2, len(users))
+		assert.Equal(t, int64(2), users[1].ID)
+		assert.Equal(t, "johnny", *users[0].Nickname)
+		assert.Nil(t, users[1].Nickname)
+
+		assert.True(t, fakeDB.Executed("users.list"))
+		assert.Equal(t, "select id, name, nickname from users", fakeDB.Queries("users.list")[0].SQL)
+		assert.Equal(t, "db", fakeDB.Queries()[0].ExecutionLevel)
+	})
+
+	t.Run("When query key is scripted with structs it will return rows built from the structs", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		fake.ReturnStructs(fakeDB.On("users.find_by_id"), []user{{ID: 1, Name: "john"}})
+
+		found, exc := db.ScanRow[user](fakeDB.QueryRowContext(ktx, "users.find_by_id", "select id, name, nickname from users where id = ?", 1))
+		assert.Nil(t, exc)
+		assert.Equal(t, user{ID: 1, Name: "john"}, found)
+		assert.Equal(t, []interface{}{1}, fakeDB.Queries("users.find_by_id")[0].Args)
+	})
+
+	t.Run("When query key is scripted several times it will answer in order and repeat the last one", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		fakeDB.On("users.delete").ReturnException(exception.Throw(errors.New("deadlock"), exception.WithType(exception.Conflict)))
+		fakeDB.On("users.delete").ReturnResult(0, 1)
+
+		_, exc := fakeDB.ExecContext(ktx, "users.delete", "delete from users")
+		assert.Equal(t, exception.Conflict, exc.Type())
+
+		for i := 0; i < 2; i++ {
+			result, exc := fakeDB.ExecContext(ktx, "users.delete", "delete from users")
+			assert.Nil(t, exc)
+
+			rowsAffected, _ := result.RowsAffected()
+			assert.Equal(t, int64(1), rowsAffected)
+		}
+	})
+
+	t.Run("When query key is not scripted it will return empty response unless strict", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+
+		var id int64
+		assert.Equal(t, exception.NotFound, fakeDB.QueryRowContext(ktx, "users.find_by_id", "select id from users").Scan(&id).Type())
+
+		rows, exc := fakeDB.QueryContext(ktx, "users.list", "select id from users")
+		assert.Nil(t, exc)
+		assert.False(t, rows.Next())
+
+		strictDB := fake.Fabricate(fake.WithStrict(), fake.WithDialect(db.Postgres))
+		_, exc = strictDB.ExecContext(ktx, "users.delete", "delete from users")
+		assert.Equal(t, exception.Unexpected, exc.Type())
+		assert.Equal(t, db.Postgres, strictDB.Dialect())
+	})
+
+	t.Run("When response expect SQL and args it will only answer the matching query", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		fakeDB.On("users.rename").Expect("update users set name = ? where id = ?", "john", int64(1)).ReturnResult(0, 1)
+
+		result, exc := fakeDB.ExecNamed(ktx, "users.rename", "update users set name = :name where id = :id", map[string]interface{}{"id": 1, "name": "john"})
+		assert.Nil(t, exc)
+		rowsAffected, _ := result.RowsAffected()
+		assert.Equal(t, int64(1), rowsAffected)
+
+		_, exc = fakeDB.ExecContext(ktx, "users.rename", "update users set name = ? where id = ?", "jane", 1)
+		assert.Equal(t, "unexpected query", exc.Title())
+	})
+
+	t.Run("When transaction succeed it will be committed", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		fakeDB.On("users.find_by_id").ReturnRows([]string{"id", "name", "nickname"}, []interface{}{1, "john", nil})
+
+		committed := false
+		fakeDB.On("users.rename").ReturnResult(0, 1)
+
+		repository := &userRepository{db: fakeDB}
+		assert.Nil(t, fakeDB.Transaction(ktx, "outer", func(tx db.TX) exception.Exception {
+			tx.OnCommit(ktx, func(ktx kontext.Context) { committed = true })
+			return repository.rename(ktx, 1, "johnny")
+		}))
+
+		assert.True(t, committed)
+		assert.True(t, fakeDB.Committed("rename-user"))
+		assert.True(t, fakeDB.Committed("outer"))
+
+		queries := fakeDB.Queries("users.rename")
+		assert.Equal(t, "tx", queries[0].ExecutionLevel)
+		assert.Equal(t, "rename-user", queries[0].TransactionKey)
+		assert.Equal(t, "update users set name = ? where id = ?", queries[0].SQL)
+		assert.Equal(t, []interface{}{"johnny", int64(1)}, queries[0].Args)
+	})
+
+	t.Run("When transaction failed it will be rolled back", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+
+		rolledBack := false
+		exc := fakeDB.Transaction(ktx, "outer", func(tx db.TX) exception.Exception {
+			tx.OnRollback(func(ktx kontext.Context) { rolledBack = true })
+			return (&userRepository{db: fakeDB}).rename(ktx, 1, "johnny")
+		})
+
+		assert.Equal(t, exception.NotFound, exc.Type())
+		assert.True(t, rolledBack)
+		assert.True(t, fakeDB.RolledBack("rename-user"))
+		assert.False(t, fakeDB.Executed("users.rename"))
+
+		nested := fakeDB.Transactions("outer")
+		assert.Equal(t, 1, len(nested))
+		assert.False(t, nested[0].Nested)
+	})
+
+	t.Run("When nested transaction failed only nested transaction is rolled back", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+
+		var callbacks []string
+		assert.Nil(t, fakeDB.Transaction(ktx, "outer", func(tx db.TX) exception.Exception {
+			_ = tx.Transaction(ktx, "inner", func(tx db.TX) exception.Exception {
+				tx.OnCommit(ktx, func(ktx kontext.Context) { callbacks = append(callbacks, "inner commit") })
+				tx.OnRollback(func(ktx kontext.Context) { callbacks = append(callbacks, "inner rollback") })
+				return exception.Throw(errors.New("unexpected error"))
+			})
+
+			return nil
+		}))
+
+		assert.Equal(t, []string{"inner rollback"}, callbacks)
+		assert.True(t, fakeDB.RolledBack("inner"))
+		assert.True(t, fakeDB.Transactions("inner")[0].Nested)
+		assert.True(t, fakeDB.Committed("outer"))
+	})
+
+	t.Run("Others", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		assert.Nil(t, fakeDB.Ping(ktx))
+
+		fakeDB.FailPing(exception.Throw(sql.ErrConnDone, exception.WithType(exception.Unavailable)))
+		assert.Equal(t, exception.Unavailable, fakeDB.Ping(ktx).Type())
+		assert.Nil(t, fakeDB.Eject())
+		assert.Equal(t, "fake", fakeDB.Stats().InstanceName)
+
+		var committed kontext.Context
+		fakeDB.OnCommit(ktx, func(ktx kontext.Context) { committed = ktx })
+		assert.Same(t, ktx, committed)
+
+		_, exc := fakeDB.QueryNamed(ktx, "users.list", "select id from users where id in (:ids)", map[string]interface{}{"ids": []int{1, 2}})
+		assert.Nil(t, exc)
+		assert.Equal(t, []interface{}{1, 2}, fakeDB.Queries("users.list")[0].Args)
+
+		fakeDB.Reset()
+		assert.Nil(t, fakeDB.Ping(ktx))
+		assert.Equal(t, 0, len(fakeDB.Queries()))
+
+		var fakeAsDB db.DB = fakeDB
+		assert.NotNil(t, fakeAsDB)
+	})
+}
@@ -0,0 +1,232 @@
+package fake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// golden file content written by Recorder and read by Replay
+type golden struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	ExecutionLevel string       `json:"execution_level"`
+	Function       string       `json:"function"`
+	TransactionKey string       `json:"transaction_key,omitempty"`
+	Key            string       `json:"key"`
+	SQL            string       `json:"sql"`
+	Args           []value      `json:"args,omitempty"`
+	Columns        []string     `json:"columns,omitempty"`
+	Rows           [][]value    `json:"rows,omitempty"`
+	LastInsertID   int64        `json:"last_insert_id,omitempty"`
+	RowsAffected   int64        `json:"rows_affected,omitempty"`
+	Exception      *recordedExc `json:"exception,omitempty"`
+}
+
+type recordedExc struct {
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Message  string `json:"message"`
+	Sentinel string `json:"sentinel,omitempty"`
+}
+
+// sentinels wrapped by recorded exception, it is wrapped again when replayed so errors.Is keep working
+var sentinels = map[string]error{
+	"sql.ErrNoRows":            sql.ErrNoRows,
+	"sql.ErrTxDone":            sql.ErrTxDone,
+	"sql.ErrConnDone":          sql.ErrConnDone,
+	"driver.ErrBadConn":        driver.ErrBadConn,
+	"context.Canceled":         context.Canceled,
+	"context.DeadlineExceeded": context.DeadlineExceeded,
+}
+
+func recordException(exc exception.Exception) *recordedExc {
+	if exc == nil {
+		return nil
+	}
+
+	recorded := &recordedExc{Type: exc.Type().String(), Title: exc.Title(), Detail: exc.Detail(), Message: exc.Error()}
+	for name, sentinel := range sentinels {
+		if errors.Is(exc, sentinel) {
+			recorded.Sentinel = name
+		}
+	}
+
+	return recorded
+}
+
+func (r *recordedExc) exception() exception.Exception {
+	if r == nil {
+		return nil
+	}
+
+	exceptionType := exception.Unexpected
+	for t := exception.Unexpected; t <= exception.Unavailable; t++ {
+		if t.String() == r.Type {
+			exceptionType = t
+		}
+	}
+
+	return exception.Throw(&replayedError{message: r.Message, sentinel: sentinels[r.Sentinel]}, exception.WithType(exceptionType), exception.WithTitle(r.Title), exception.WithDetail(r.Detail))
+}
+
+// replayedError has the recorded message and wrap the recorded sentinel error
+type replayedError struct {
+	message  string
+	sentinel error
+}
+
+func (e *replayedError) Error() string {
+	return e.message
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.sentinel
+}
+
+// value keep go type of recorded args and rows in the golden file so it is replayed as the same type
+type value struct {
+	v interface{}
+}
+
+type encodedValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+func recordValue(v interface{}) value {
+	if valuer, ok := v.(driver.Valuer); ok {
+		v, _ = valuer.Value()
+	}
+
+	reflected := reflect.ValueOf(v)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value{v: reflected.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value{v: int64(reflected.Uint())}
+	case reflect.Float32, reflect.Float64:
+		return value{v: reflected.Float()}
+	case reflect.Ptr:
+		if reflected.IsNil() {
+			return value{}
+		}
+
+		return recordValue(reflected.Elem().Interface())
+	}
+
+	switch v := v.(type) {
+	case nil, bool, string, []byte, time.Time:
+		return value{v: v}
+	default:
+		return value{v: fmt.Sprint(v)}
+	}
+}
+
+func (v value) MarshalJSON() ([]byte, error) {
+	var encoded encodedValue
+
+	switch x := v.v.(type) {
+	case nil:
+		encoded = encodedValue{Type: "null"}
+	case int64:
+		encoded = encodedValue{Type: "int64", Value: strconv.FormatInt(x, 10)}
+	case float64:
+		encoded = encodedValue{Type: "float64", Value: strconv.FormatFloat(x, 'g', -1, 64)}
+	case bool:
+		encoded = encodedValue{Type: "bool", Value: strconv.FormatBool(x)}
+	case string:
+		encoded = encodedValue{Type: "string", Value: x}
+	case []byte:
+		encoded = encodedValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(x)}
+	case time.Time:
+		encoded = encodedValue{Type: "time", Value: x.Format(time.RFC3339Nano)}
+	default:
+		return nil, fmt.Errorf("unsupported recorded value %T", v.v)
+	}
+
+	return json.Marshal(encoded)
+}
+
+func (v *value) UnmarshalJSON(data []byte) error {
+	var encoded encodedValue
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	var err error
+	switch encoded.Type {
+	case "null":
+		v.v = nil
+	case "int64":
+		v.v, err = strconv.ParseInt(encoded.Value, 10, 64)
+	case "float64":
+		v.v, err = strconv.ParseFloat(encoded.Value, 64)
+	case "bool":
+		v.v, err = strconv.ParseBool(encoded.Value)
+	case "string":
+		v.v = encoded.Value
+	case "bytes":
+		v.v, err = base64.StdEncoding.DecodeString(encoded.Value)
+	case "time":
+		v.v, err = time.Parse(time.RFC3339Nano, encoded.Value)
+	default:
+		err = fmt.Errorf("unsupported recorded value type %s", encoded.Type)
+	}
+
+	return err
+}
+
+func recordValues(values []interface{}) []value {
+	recorded := make([]value, len(values))
+	for i, v := range values {
+		recorded[i] = recordValue(v)
+	}
+
+	return recorded
+}
+
+// equalValues compare recorded values, time is compared regardless of its location
+func equalValues(a, b []value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		at, ok := a[i].v.(time.Time)
+		if bt, isTime := b[i].v.(time.Time); ok && isTime {
+			if !at.Equal(bt) {
+				return false
+			}
+
+			continue
+		}
+
+		if !reflect.DeepEqual(a[i].v, b[i].v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func replayValues(recorded []value) []interface{} {
+	values := make([]interface{}, len(recorded))
+	for i, v := range recorded {
+		values[i] = v.v
+	}
+
+	return values
+}
@@ -0,0 +1,89 @@
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Lock acquire in-memory lock shared by every user of the fake, it behave the same as db.Adapter Lock and recorded as "Lock" query with the name as its key
+func (f *DB) Lock(ktx kontext.Context, name string, timeout time.Duration) (db.Lock, exception.Exception) {
+	f.mutex.Lock()
+	f.queries = append(f.queries, Query{ExecutionLevel: "db", Function: "Lock", Key: name})
+	f.mutex.Unlock()
+
+	var deadline <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		f.mutex.Lock()
+		held, ok := f.locks[name]
+		if !ok {
+			released := make(chan struct{})
+			f.locks[name] = released
+			f.mutex.Unlock()
+
+			acquired := &lock{db: f, name: name, released: released}
+			go acquired.watch(ktx)
+
+			return acquired, nil
+		}
+		f.mutex.Unlock()
+
+		select {
+		case <-held:
+		case <-deadline:
+			return nil, exception.Throw(fmt.Errorf("timeout acquiring lock %s after %s", name, timeout), exception.WithType(exception.Conflict), exception.WithTitle("lock timeout"))
+		case <-ktx.Ctx().Done():
+			return nil, exception.Throw(ktx.Ctx().Err())
+		}
+	}
+}
+
+// Locked return true when the lock is currently held
+func (f *DB) Locked(name string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, ok := f.locks[name]
+	return ok
+}
+
+type lock struct {
+	db       *DB
+	name     string
+	once     sync.Once
+	released chan struct{}
+}
+
+func (l *lock) Name() string {
+	return l.name
+}
+
+func (l *lock) Release(ktx kontext.Context) exception.Exception {
+	l.once.Do(func() {
+		l.db.mutex.Lock()
+		defer l.db.mutex.Unlock()
+
+		delete(l.db.locks, l.name)
+		close(l.released)
+	})
+
+	return nil
+}
+
+func (l *lock) watch(ktx kontext.Context) {
+	select {
+	case <-ktx.Ctx().Done():
+		_ = l.Release(ktx)
+	case <-l.released:
+	}
+}
@@ -0,0 +1,49 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db/fake"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When the lock is held it will wait until released or timeout", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+
+		lock, exc := fakeDB.Lock(ktx, "daily-report", time.Second)
+		assert.Nil(t, exc)
+		assert.True(t, fakeDB.Locked("daily-report"))
+
+		_, exc = fakeDB.Lock(ktx, "daily-report", 10*time.Millisecond)
+		assert.Equal(t, exception.Conflict, exc.Type())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = lock.Release(ktx)
+		}()
+
+		lock, exc = fakeDB.Lock(ktx, "daily-report", time.Second)
+		assert.Nil(t, exc)
+		assert.Nil(t, lock.Release(ktx))
+		assert.False(t, fakeDB.Locked("daily-report"))
+		assert.Len(t, fakeDB.Queries("daily-report"), 3)
+	})
+
+	t.Run("When the kontext is cancelled it will release the lock", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		_, exc := fakeDB.Lock(kontext.Fabricate(kontext.WithDefaultContext(ctx)), "daily-report", time.Second)
+		assert.Nil(t, exc)
+
+		cancel()
+		assert.Eventually(t, func() bool { return !fakeDB.Locked("daily-report") }, time.Second, time.Millisecond)
+	})
+}
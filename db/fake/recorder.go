@@ -0,0 +1,208 @@
+package fake
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Recorder wrap db.DB and record every query key, SQL, args and its returned result or rows, use Save to write it into golden file and Replay to serve it back.
+// Rows is read entirely before returned so the recording is complete, QueryRowContext is executed using QueryContext of the wrapped DB.
+type Recorder struct {
+	recordingTX
+	db db.DB
+}
+
+// Record every query executed against database
+func Record(database db.DB) *Recorder {
+	return &Recorder{recordingTX: recordingTX{tx: database, level: "db", golden: &recording{}}, db: database}
+}
+
+// Save recorded interactions into golden file as indented JSON
+func (r *Recorder) Save(path string) exception.Exception {
+	r.golden.mutex.Lock()
+	defer r.golden.mutex.Unlock()
+
+	content, err := json.MarshalIndent(golden{Interactions: r.golden.interactions}, "", "  ")
+	if err != nil {
+		return exception.Throw(err)
+	}
+
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return exception.Throw(err)
+	}
+
+	return nil
+}
+
+// Ping wrapped database
+func (r *Recorder) Ping(ktx kontext.Context) exception.Exception {
+	return r.db.Ping(ktx)
+}
+
+// Lock using wrapped database, it is not recorded
+func (r *Recorder) Lock(ktx kontext.Context, name string, timeout time.Duration) (db.Lock, exception.Exception) {
+	return r.db.Lock(ktx, name, timeout)
+}
+
+// Stats of wrapped database
+func (r *Recorder) Stats() db.PoolStats {
+	return r.db.Stats()
+}
+
+// Eject wrapped database
+func (r *Recorder) Eject() *sql.DB {
+	return r.db.Eject()
+}
+
+type recording struct {
+	mutex        sync.Mutex
+	interactions []interaction
+}
+
+func (r *recording) append(record interaction) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.interactions = append(r.interactions, record)
+}
+
+// recordingTX record queries executed against db.TX, Recorder use it to record queries outside of transaction
+type recordingTX struct {
+	tx             db.TX
+	level          string
+	transactionKey string
+	golden         *recording
+}
+
+func (t *recordingTX) Transaction(ktx kontext.Context, transactionKey string, f func(tx db.TX) exception.Exception, opts ...db.TransactionOption) exception.Exception {
+	return t.tx.Transaction(ktx, transactionKey, func(tx db.TX) exception.Exception {
+		return f(&recordingTX{tx: tx, level: "tx", transactionKey: transactionKey, golden: t.golden})
+	}, opts...)
+}
+
+func (t *recordingTX) ExecContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (db.Result, exception.Exception) {
+	record := t.interaction("ExecContext", queryKey, query, args)
+
+	result, exc := t.tx.ExecContext(ktx, queryKey, query, args...)
+	if exc == nil {
+		record.LastInsertID, _ = result.LastInsertId()
+		record.RowsAffected, _ = result.RowsAffected()
+	}
+
+	record.Exception = recordException(exc)
+	t.golden.append(record)
+
+	return result, exc
+}
+
+func (t *recordingTX) QueryContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (db.Rows, exception.Exception) {
+	record := t.interaction("QueryContext", queryKey, query, args)
+
+	columns, values, exc := t.query(ktx, queryKey, query, args)
+	record.Columns = columns
+	for _, row := range values {
+		record.Rows = append(record.Rows, recordValues(row))
+	}
+	record.Exception = recordException(exc)
+	t.golden.append(record)
+
+	if exc != nil {
+		return &rows{}, exc
+	}
+
+	return &rows{columns: columns, values: values, index: -1}, nil
+}
+
+func (t *recordingTX) QueryRowContext(ktx kontext.Context, queryKey, query string, args ...interface{}) db.Row {
+	record := t.interaction("QueryRowContext", queryKey, query, args)
+
+	columns, values, exc := t.query(ktx, queryKey, query, args)
+	if exc == nil && len(values) == 0 {
+		exc = exception.Throw(sql.ErrNoRows, exception.WithType(exception.NotFound))
+	}
+
+	record.Columns = columns
+	if len(values) > 0 {
+		record.Rows = [][]value{recordValues(values[0])}
+	}
+	record.Exception = recordException(exc)
+	t.golden.append(record)
+
+	if exc != nil {
+		return &row{exc: exc}
+	}
+
+	return &row{values: values[0]}
+}
+
+func (t *recordingTX) ExecNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (db.Result, exception.Exception) {
+	query, args, exc := db.BindNamed(t.tx.Dialect(), query, arg)
+	if exc != nil {
+		return &result{}, exc
+	}
+
+	return t.ExecContext(ktx, queryKey, query, args...)
+}
+
+func (t *recordingTX) QueryNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (db.Rows, exception.Exception) {
+	query, args, exc := db.BindNamed(t.tx.Dialect(), query, arg)
+	if exc != nil {
+		return &rows{}, exc
+	}
+
+	return t.QueryContext(ktx, queryKey, query, args...)
+}
+
+func (t *recordingTX) OnCommit(ktx kontext.Context, f func(ktx kontext.Context)) {
+	t.tx.OnCommit(ktx, f)
+}
+
+func (t *recordingTX) OnRollback(f func(ktx kontext.Context)) {
+	t.tx.OnRollback(f)
+}
+
+func (t *recordingTX) Dialect() db.Dialect {
+	return t.tx.Dialect()
+}
+
+func (t *recordingTX) interaction(function, queryKey, query string, args []interface{}) interaction {
+	return interaction{ExecutionLevel: t.level, Function: function, TransactionKey: t.transactionKey, Key: queryKey, SQL: query, Args: recordValues(args)}
+}
+
+// query read every row of the query as driver values
+func (t *recordingTX) query(ktx kontext.Context, queryKey, query string, args []interface{}) ([]string, [][]interface{}, exception.Exception) {
+	queried, exc := t.tx.QueryContext(ktx, queryKey, query, args...)
+	if exc != nil {
+		return nil, nil, exc
+	}
+	defer queried.Close()
+
+	columns, exc := queried.Columns()
+	if exc != nil {
+		return nil, nil, exc
+	}
+
+	var values [][]interface{}
+	for queried.Next() {
+		row := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+
+		if exc := queried.Scan(dest...); exc != nil {
+			return nil, nil, exc
+		}
+
+		values = append(values, row)
+	}
+
+	return columns, values, queried.Err()
+}
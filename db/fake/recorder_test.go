@@ -0,0 +1,130 @@
+package fake_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/fake"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type post struct {
+	ID        int64     `db:"id"`
+	Title     string    `db:"title"`
+	Body      []byte    `db:"body"`
+	Rating    float64   `db:"rating"`
+	Draft     bool      `db:"draft"`
+	Editor    *string   `db:"editor"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func TestRecorder(t *testing.T) {
+	ktx := kontext.Fabricate()
+	golden := filepath.Join(t.TempDir(), "posts.golden.json")
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// interact is the code under test, executed against recorder and replayer
+	interact := func(t *testing.T, database db.DB) {
+		exc := database.Transaction(ktx, "publish-post", func(tx db.TX) exception.Exception {
+			result, exc := tx.ExecContext(ktx, "posts.insert", "INSERT INTO posts (title, body, rating, draft, created_at) VALUES (?, ?, ?, ?, ?)", "hello", []byte("world"), 4.5, false, createdAt)
+			if exc != nil {
+				return exc
+			}
+
+			id, _ := result.LastInsertId()
+			assert.Equal(t, int64(1), id)
+
+			_, exc = tx.ExecNamed(ktx, "posts.publish", "UPDATE posts SET draft = :draft WHERE id = :id", map[string]interface{}{"id": id, "draft": false})
+			return exc
+		})
+		assert.Nil(t, exc)
+
+		rows, exc := database.QueryContext(ktx, "posts.list", "SELECT id, title, body, rating, draft, editor, created_at FROM posts")
+		assert.Nil(t, exc)
+
+		posts, exc := db.ScanAll[post](rows)
+		assert.Nil(t, exc)
+		assert.Equal(t, []post{{ID: 1, Title: "hello", Body: []byte("world"), Rating: 4.5, CreatedAt: createdAt}}, posts)
+
+		var title string
+		exc = database.QueryRowContext(ktx, "posts.find_by_id", "SELECT title FROM posts WHERE id = ?", 2).Scan(&title)
+		assert.Equal(t, exception.NotFound, exc.Type())
+		assert.ErrorIs(t, exc, sql.ErrNoRows)
+
+		_, exc = database.ExecContext(ktx, "posts.insert", "INSERT INTO posts (id, title, body, rating, draft, created_at) VALUES (1, 'hello', '', 0, 0, CURRENT_TIMESTAMP)")
+		assert.Equal(t, exception.Duplicated, exc.Type())
+	}
+
+	t.Run("When wrapping real database it will record every interaction into golden file", func(t *testing.T) {
+		sqldb, exc := sqlite.Fabricate("recorder_db", db.Config{Name: sqlite.Memory})
+		assert.Nil(t, exc)
+		defer sqldb.Eject().Close()
+
+		_, exc = sqldb.ExecContext(ktx, "posts.create_table", "CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT NOT NULL, body BLOB, rating REAL, draft BOOLEAN, editor TEXT, created_at DATETIME)")
+		assert.Nil(t, exc)
+
+		recorder := fake.Record(sqldb)
+		interact(t, recorder)
+		assert.Nil(t, recorder.Save(golden))
+		assert.Equal(t, sqlite.Dialect, recorder.Dialect())
+		assert.Nil(t, recorder.Ping(ktx))
+
+		content, err := os.ReadFile(golden)
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), `"key": "posts.publish"`)
+		assert.Contains(t, string(content), `"transaction_key": "publish-post"`)
+	})
+
+	t.Run("When replaying golden file it will serve the recorded interactions offline", func(t *testing.T) {
+		replayer, exc := fake.Replay(golden, fake.WithDialect(sqlite.Dialect))
+		assert.Nil(t, exc)
+
+		interact(t, replayer)
+		assert.True(t, replayer.Committed("publish-post"))
+		assert.Equal(t, []interface{}{false, int64(1)}, replayer.Queries("posts.publish")[0].Args)
+	})
+
+	t.Run("When replayed query differ from the recording it will return exception unless matching is loose", func(t *testing.T) {
+		replayer, exc := fake.Replay(golden, fake.WithDialect(sqlite.Dialect))
+		assert.Nil(t, exc)
+
+		var title string
+		exc = replayer.QueryRowContext(ktx, "posts.find_by_id", "SELECT title FROM posts WHERE id = ?", 3).Scan(&title)
+		assert.Equal(t, "unexpected query", exc.Title())
+
+		_, exc = replayer.QueryContext(ktx, "posts.list", "SELECT id, title FROM posts")
+		assert.Equal(t, "unexpected query", exc.Title())
+
+		_, exc = replayer.ExecContext(ktx, "posts.insert", "INSERT INTO posts (title) VALUES (?)", "hello")
+		assert.Equal(t, "unexpected query", exc.Title())
+
+		result, exc := replayer.ExecContext(ktx, "posts.insert", "INSERT INTO posts (title, body, rating, draft, created_at) VALUES (?, ?, ?, ?, ?)", "hello", []byte("world"), 4.5, false, createdAt)
+		assert.Nil(t, exc)
+
+		id, _ := result.LastInsertId()
+		assert.Equal(t, int64(1), id)
+
+		loose, exc := fake.Replay(golden, fake.WithDialect(sqlite.Dialect), fake.WithLooseMatching())
+		assert.Nil(t, exc)
+
+		exc = loose.QueryRowContext(ktx, "posts.find_by_id", "SELECT title FROM posts WHERE id = ?", 3).Scan(&title)
+		assert.Equal(t, exception.NotFound, exc.Type())
+	})
+
+	t.Run("When golden file is invalid it will return exception", func(t *testing.T) {
+		_, exc := fake.Replay(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Equal(t, exception.NotFound, exc.Type())
+
+		invalid := filepath.Join(t.TempDir(), "invalid.json")
+		assert.Nil(t, os.WriteFile(invalid, []byte(`{"interactions": [{"args": [{"type": "complex"}]}]}`), 0o644))
+		_, exc = fake.Replay(invalid)
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+}
@@ -0,0 +1,45 @@
+package fake
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Replay fabricate strict fake DB serving interactions of golden file written by Recorder.
+// Each query key is answered in the recorded order and the last recording is repeated.
+// Query executed with different SQL or args than the recording return exception titled "unexpected query", use WithLooseMatching to only match the query key.
+func Replay(path string, opts ...Option) (*DB, exception.Exception) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, exception.Throw(err, exception.WithType(exception.NotFound))
+	}
+
+	var recorded golden
+	if err := json.Unmarshal(content, &recorded); err != nil {
+		return nil, exception.Throw(err, exception.WithType(exception.BadInput))
+	}
+
+	fakeDB := Fabricate(append([]Option{WithStrict()}, opts...)...)
+	for _, record := range recorded.Interactions {
+		response := fakeDB.On(record.Key).ReturnException(record.Exception.exception())
+		if !fakeDB.config.loose {
+			response.Expect(record.SQL, replayValues(record.Args)...)
+		}
+
+		switch record.Function {
+		case "ExecContext":
+			response.ReturnResult(record.LastInsertID, record.RowsAffected)
+		default:
+			rows := make([][]interface{}, 0, len(record.Rows))
+			for _, row := range record.Rows {
+				rows = append(rows, replayValues(row))
+			}
+
+			response.ReturnRows(record.Columns, rows...)
+		}
+	}
+
+	return fakeDB, nil
+}
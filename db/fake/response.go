@@ -0,0 +1,226 @@
+package fake
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Response scripted for a query key
+type Response struct {
+	lastInsertID int64
+	rowsAffected int64
+	columns      []string
+	rows         [][]interface{}
+	exc          exception.Exception
+
+	expected bool
+	sql      string
+	args     []interface{}
+}
+
+// Expect make the response only answer query executed with the same SQL and args, other query return exception titled "unexpected query".
+// Args is compared after it is converted the same way as Recorder, so int and int64 of the same number is equal.
+func (r *Response) Expect(sql string, args ...interface{}) *Response {
+	r.expected = true
+	r.sql = sql
+	r.args = args
+	return r
+}
+
+// match return exception when the query is not the expected one
+func (r *Response) match(query Query) exception.Exception {
+	if !r.expected || (r.sql == query.SQL && equalValues(recordValues(r.args), recordValues(query.Args))) {
+		return nil
+	}
+
+	return exception.Throw(fmt.Errorf("query key %s expected %s with args %v, got %s with args %v", query.Key, r.sql, r.args, query.SQL, query.Args), exception.WithTitle("unexpected query"))
+}
+
+// ReturnResult answer exec with given result
+func (r *Response) ReturnResult(lastInsertID, rowsAffected int64) *Response {
+	r.lastInsertID = lastInsertID
+	r.rowsAffected = rowsAffected
+	return r
+}
+
+// ReturnRows answer query with rows of values ordered the same as columns
+func (r *Response) ReturnRows(columns []string, rows ...[]interface{}) *Response {
+	r.columns = columns
+	r.rows = rows
+	return r
+}
+
+// ReturnStructs answer query with rows built from struct values mapped by `db` tag, see db.Columns
+func ReturnStructs[T any](r *Response, values []T) *Response {
+	rows := make([][]interface{}, 0, len(values))
+	for _, value := range values {
+		rows = append(rows, db.Values(value))
+	}
+
+	return r.ReturnRows(db.Columns[T](), rows...)
+}
+
+// ReturnException answer query with exception
+func (r *Response) ReturnException(exc exception.Exception) *Response {
+	r.exc = exc
+	return r
+}
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r *result) LastInsertId() (int64, exception.Exception) {
+	return r.lastInsertID, nil
+}
+
+func (r *result) RowsAffected() (int64, exception.Exception) {
+	return r.rowsAffected, nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]interface{}
+	index   int
+	closed  bool
+}
+
+func (r *rows) Close() exception.Exception {
+	r.closed = true
+	return nil
+}
+
+func (r *rows) Columns() ([]string, exception.Exception) {
+	return r.columns, nil
+}
+
+func (r *rows) Err() exception.Exception {
+	return nil
+}
+
+func (r *rows) Next() bool {
+	if r.closed || r.index+1 >= len(r.values) {
+		return false
+	}
+
+	r.index++
+	return true
+}
+
+func (r *rows) NextResultSet() bool {
+	return false
+}
+
+func (r *rows) Scan(dest ...interface{}) exception.Exception {
+	if r.index < 0 || r.index >= len(r.values) {
+		return exception.Throw(fmt.Errorf("scan called without calling next"))
+	}
+
+	return scan(r.values[r.index], dest)
+}
+
+type row struct {
+	values []interface{}
+	exc    exception.Exception
+}
+
+func (r *row) Scan(dest ...interface{}) exception.Exception {
+	if r.exc != nil {
+		return r.exc
+	}
+
+	return scan(r.values, dest)
+}
+
+// scan assign canned values into dest, value is converted between the same kind family, bytes and string, or number into bool similar to database/sql
+func scan(values []interface{}, dest []interface{}) exception.Exception {
+	if len(values) != len(dest) {
+		return exception.Throw(fmt.Errorf("expected %d destination arguments in scan, not %d", len(values), len(dest)))
+	}
+
+	for i, value := range values {
+		if exc := assign(dest[i], value); exc != nil {
+			return exc
+		}
+	}
+
+	return nil
+}
+
+func assign(dest, value interface{}) exception.Exception {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		if err := scanner.Scan(value); err != nil {
+			return exception.Throw(err)
+		}
+
+		return nil
+	}
+
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return exception.Throw(fmt.Errorf("destination must be non nil pointer, got %T", dest))
+	}
+	target = target.Elem()
+
+	source := reflect.ValueOf(value)
+	if !source.IsValid() {
+		switch target.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		default:
+			return exception.Throw(fmt.Errorf("converting NULL to %s is unsupported", target.Type()))
+		}
+	}
+
+	if target.Kind() == reflect.Ptr && source.Type() != target.Type() {
+		pointer := reflect.New(target.Type().Elem())
+		if exc := assign(pointer.Interface(), value); exc != nil {
+			return exc
+		}
+
+		target.Set(pointer)
+		return nil
+	}
+
+	switch {
+	case source.Kind() == reflect.Slice && source.Type().Elem().Kind() == reflect.Uint8 && target.Kind() == reflect.String:
+		target.SetString(string(source.Bytes()))
+	case source.Kind() == reflect.String && target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.Uint8:
+		target.SetBytes([]byte(source.String()))
+	case family(source.Kind()) == 1 && target.Kind() == reflect.Bool:
+		target.SetBool(!source.IsZero())
+	case source.Type().AssignableTo(target.Type()):
+		target.Set(source)
+	case sameFamily(source.Kind(), target.Kind()) && source.Type().ConvertibleTo(target.Type()):
+		target.Set(source.Convert(target.Type()))
+	default:
+		return exception.Throw(fmt.Errorf("converting %T to %s is unsupported", value, target.Type()))
+	}
+
+	return nil
+}
+
+func sameFamily(a, b reflect.Kind) bool {
+	return family(a) != 0 && family(a) == family(b)
+}
+
+func family(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return 1
+	case reflect.String:
+		return 2
+	case reflect.Bool:
+		return 3
+	case reflect.Slice:
+		return 4
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,112 @@
+package fake
+
+import (
+	"sync"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// TX is fake of db.TX created by DB.Transaction, it share scripted responses and records with its DB
+type TX struct {
+	db             *DB
+	transactionKey string
+	mutex          sync.Mutex
+	onCommit       []func(ktx kontext.Context)
+	onRollback     []func(ktx kontext.Context)
+}
+
+// run f and record the transaction, nested transaction hand over its commit callbacks into parent like savepoint
+func (t *TX) run(ktx kontext.Context, nested bool, fn func(tx db.TX) exception.Exception) exception.Exception {
+	exc := fn(t)
+
+	t.db.finish(Transaction{Key: t.transactionKey, Nested: nested, Committed: exc == nil, RolledBack: exc != nil, Exception: exc})
+
+	if exc != nil {
+		for _, callback := range t.onRollback {
+			callback(ktx)
+		}
+
+		return exc
+	}
+
+	if !nested {
+		for _, callback := range t.onCommit {
+			callback(ktx)
+		}
+	}
+
+	return nil
+}
+
+// Transaction create nested transaction, only changes of f is rolled back when it return exception
+func (t *TX) Transaction(ktx kontext.Context, transactionKey string, fn func(tx db.TX) exception.Exception, opts ...db.TransactionOption) exception.Exception {
+	nestedTx := &TX{db: t.db, transactionKey: transactionKey}
+
+	exc := nestedTx.run(ktx, true, fn)
+	if exc == nil {
+		t.mutex.Lock()
+		t.onCommit = append(t.onCommit, nestedTx.onCommit...)
+		t.onRollback = append(t.onRollback, nestedTx.onRollback...)
+		t.mutex.Unlock()
+	}
+
+	return exc
+}
+
+// ExecContext answer with scripted result of query key
+func (t *TX) ExecContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (db.Result, exception.Exception) {
+	return t.db.exec(Query{ExecutionLevel: "tx", Function: "ExecContext", TransactionKey: t.transactionKey, Key: queryKey, SQL: query, Args: args})
+}
+
+// QueryContext answer with scripted rows of query key
+func (t *TX) QueryContext(ktx kontext.Context, queryKey, query string, args ...interface{}) (db.Rows, exception.Exception) {
+	return t.db.query(Query{ExecutionLevel: "tx", Function: "QueryContext", TransactionKey: t.transactionKey, Key: queryKey, SQL: query, Args: args})
+}
+
+// QueryRowContext answer with the first scripted row of query key
+func (t *TX) QueryRowContext(ktx kontext.Context, queryKey, query string, args ...interface{}) db.Row {
+	return t.db.queryRow(Query{ExecutionLevel: "tx", Function: "QueryRowContext", TransactionKey: t.transactionKey, Key: queryKey, SQL: query, Args: args})
+}
+
+// ExecNamed bind named parameters using db.BindNamed and answer with scripted result of query key
+func (t *TX) ExecNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (db.Result, exception.Exception) {
+	query, args, exc := db.BindNamed(t.db.config.dialect, query, arg)
+	if exc != nil {
+		return &result{}, exc
+	}
+
+	return t.ExecContext(ktx, queryKey, query, args...)
+}
+
+// QueryNamed bind named parameters using db.BindNamed and answer with scripted rows of query key
+func (t *TX) QueryNamed(ktx kontext.Context, queryKey, query string, arg interface{}) (db.Rows, exception.Exception) {
+	query, args, exc := db.BindNamed(t.db.config.dialect, query, arg)
+	if exc != nil {
+		return &rows{}, exc
+	}
+
+	return t.QueryContext(ktx, queryKey, query, args...)
+}
+
+// OnCommit register f to be executed with kontext of the transaction after it is committed
+func (t *TX) OnCommit(ktx kontext.Context, fn func(ktx kontext.Context)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// OnRollback register f to be executed after the transaction is rolled back
+func (t *TX) OnRollback(fn func(ktx kontext.Context)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.onRollback = append(t.onRollback, fn)
+}
+
+// Dialect of fake database
+func (t *TX) Dialect() db.Dialect {
+	return t.db.config.dialect
+}
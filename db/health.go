@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Health of fabricated instance reported by its health probe
+type Health struct {
+	// Degraded when ping failed consecutively as many as the failure threshold
+	Degraded            bool
+	ConsecutiveFailures int
+	CheckedAt           time.Time
+
+	// Exception of the latest failed ping, nil after ping succeed
+	Exception exception.Exception
+}
+
+// PoolStats of database connection pool
+type PoolStats struct {
+	InstanceName string
+	Dialect      string
+
+	MaxOpenConnections int
+	OpenConnections    int
+	InUse              int
+	Idle               int
+
+	WaitCount         int64
+	WaitDuration      time.Duration
+	MaxIdleClosed     int64
+	MaxIdleTimeClosed int64
+	MaxLifetimeClosed int64
+
+	Health Health
+}
+
+// WithHealthProbe ping fabricated instance every interval in the background, the instance is marked as degraded after failureThreshold consecutive failures and recovered on the next succeed ping.
+// It is only applied by Fabricate when the instance is fabricated for the first time, the probe is stopped by CloseAll.
+func WithHealthProbe(interval time.Duration, failureThreshold int) Option {
+	return func(c *Config) {
+		c.healthProbeInterval = interval
+		c.healthProbeFailureThreshold = failureThreshold
+	}
+}
+
+// Stats of every fabricated instance ordered by dialect and instance name
+func Stats() []PoolStats {
+	var stats []PoolStats
+
+	instanceList.Range(func(key, value interface{}) bool {
+		stats = append(stats, value.(*instance).stats())
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Dialect != stats[j].Dialect {
+			return stats[i].Dialect < stats[j].Dialect
+		}
+
+		return stats[i].InstanceName < stats[j].InstanceName
+	})
+
+	return stats
+}
+
+// InstanceStats of fabricated instance. If the same instance name fabricated by several dialects, MySQL instance is returned first.
+func InstanceStats(instanceName string) (PoolStats, exception.Exception) {
+	for _, dialect := range knownDialects() {
+		if val, ok := instanceList.Load(instanceKey(dialect, instanceName)); ok {
+			return val.(*instance).stats(), nil
+		}
+	}
+
+	return PoolStats{}, exception.Throw(errors.New("unexpected error"), exception.WithType(exception.NotFound))
+}
+
+func (i *instance) stats() PoolStats {
+	stats := adaptPoolStats(i.name, i.dialect, i.db.Stats())
+	if i.probe != nil {
+		stats.Health = i.probe.health()
+	}
+
+	return stats
+}
+
+func adaptPoolStats(instanceName string, dialect Dialect, dbStats sql.DBStats) PoolStats {
+	return PoolStats{
+		InstanceName:       instanceName,
+		Dialect:            dialect.Name(),
+		MaxOpenConnections: dbStats.MaxOpenConnections,
+		OpenConnections:    dbStats.OpenConnections,
+		InUse:              dbStats.InUse,
+		Idle:               dbStats.Idle,
+		WaitCount:          dbStats.WaitCount,
+		WaitDuration:       dbStats.WaitDuration,
+		MaxIdleClosed:      dbStats.MaxIdleClosed,
+		MaxIdleTimeClosed:  dbStats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  dbStats.MaxLifetimeClosed,
+	}
+}
+
+// withInstanceName mark adapter with the instance name it is fabricated from
+func withInstanceName(instanceName string) Option {
+	return func(c *Config) {
+		c.instanceName = instanceName
+	}
+}
+
+type healthProbe struct {
+	dialect  Dialect
+	mutex    sync.RWMutex
+	current  Health
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func startHealthProbe(db *sql.DB, dialect Dialect, interval time.Duration, failureThreshold int) *healthProbe {
+	if failureThreshold <= 0 {
+		failureThreshold = 1
+	}
+
+	probe := &healthProbe{dialect: dialect, done: make(chan struct{})}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-probe.done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				err := db.PingContext(ctx)
+				cancel()
+
+				probe.record(err, failureThreshold)
+			}
+		}
+	}()
+
+	return probe
+}
+
+func (p *healthProbe) record(err error, failureThreshold int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.current.CheckedAt = time.Now()
+
+	if err == nil {
+		p.current.Degraded = false
+		p.current.ConsecutiveFailures = 0
+		p.current.Exception = nil
+		return
+	}
+
+	p.current.ConsecutiveFailures++
+	p.current.Exception = translate(p.dialect, err)
+	p.current.Degraded = p.current.ConsecutiveFailures >= failureThreshold
+}
+
+func (p *healthProbe) health() Health {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.current
+}
+
+func (p *healthProbe) stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
@@ -0,0 +1,76 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When rows is still open it will be reported as in use connection", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		adapter := db.Adapt(sqldb)
+		rows, exc := adapter.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+
+		stats := adapter.Stats()
+		assert.Equal(t, "mysql", stats.Dialect)
+		assert.Equal(t, 1, stats.InUse)
+		assert.Equal(t, 0, stats.Idle)
+		assert.False(t, stats.Health.Degraded)
+
+		assert.Nil(t, rows.Close())
+		assert.Equal(t, 1, adapter.Stats().Idle)
+	})
+
+	t.Run("When fabricated with health probe it will mark instance degraded after consecutive ping failures", func(t *testing.T) {
+		sqldb, exc := sqlite.Fabricate("health_db", db.Config{Name: sqlite.Memory}, db.WithHealthProbe(5*time.Millisecond, 2))
+		assert.Nil(t, exc)
+		assert.Nil(t, sqldb.Ping(ktx))
+
+		assert.Eventually(t, func() bool {
+			return !sqldb.Stats().Health.CheckedAt.IsZero()
+		}, time.Second, time.Millisecond)
+
+		stats, exc := db.InstanceStats("health_db")
+		assert.Nil(t, exc)
+		assert.Equal(t, "health_db", stats.InstanceName)
+		assert.Equal(t, "sqlite", stats.Dialect)
+		assert.False(t, stats.Health.Degraded)
+
+		found := false
+		for _, stats := range db.Stats() {
+			found = found || stats.InstanceName == "health_db"
+		}
+		assert.True(t, found)
+
+		assert.Nil(t, sqldb.Eject().Close())
+		assert.Eventually(t, func() bool {
+			return sqldb.Stats().Health.Degraded
+		}, time.Second, time.Millisecond)
+
+		health := sqldb.Stats().Health
+		assert.GreaterOrEqual(t, health.ConsecutiveFailures, 2)
+		assert.NotNil(t, health.Exception)
+
+		_, exc = db.InstanceStats("unknown_db")
+		assert.Equal(t, exception.NotFound, exc.Type())
+
+		db.CloseAll()
+	})
+}
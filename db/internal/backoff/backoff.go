@@ -0,0 +1,27 @@
+// Package backoff compute retry delay shared by transaction retry and outbox relay.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Exponential return exponential backoff with equal jitter of given retry started from 1, it is base doubled on each retry and capped at max.
+// Half of the backoff is fixed and the other half is random so concurrent retries does not collide again.
+func Exponential(base, max time.Duration, retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+
+	backoff := max
+	if shift := uint(retry - 1); shift < 63 && base <= max>>shift {
+		backoff = base << shift
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
@@ -0,0 +1,22 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db/internal/backoff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponential(t *testing.T) {
+	t.Run("When retry grow it will double the backoff until it is capped", func(t *testing.T) {
+		for retry, expected := range map[int]time.Duration{0: 10 * time.Millisecond, 1: 10 * time.Millisecond, 3: 40 * time.Millisecond, 10: time.Second, 100: time.Second} {
+			delay := backoff.Exponential(10*time.Millisecond, time.Second, retry)
+			assert.True(t, delay >= expected/2 && delay <= expected, "retry %d got %s", retry, delay)
+		}
+	})
+
+	t.Run("When max is zero it will not wait", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), backoff.Exponential(0, 0, 1))
+	})
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Lock is distributed advisory lock acquired using DB Lock
+type Lock interface {
+	// Name of the lock
+	Name() string
+
+	// Release the lock, calling it more than once return the result of the first call
+	Release(ktx kontext.Context) exception.Exception
+}
+
+// lockPollInterval of postgres pg_try_advisory_lock while waiting for the lock
+var lockPollInterval = 100 * time.Millisecond
+
+// ErrLockNotSupported is wrapped by exception returned from DB Lock when the dialect does not have advisory lock
+var ErrLockNotSupported = errors.New("advisory lock is not supported")
+
+// WithLockReleaseTimeout bound releasing advisory lock acquired using DB Lock, default to 5 seconds
+func WithLockReleaseTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.lockReleaseTimeout = timeout
+	}
+}
+
+// advisoryLock is held by single connection pinned from the pool until it is released
+type advisoryLock struct {
+	name           string
+	scoped         string
+	conn           *sql.Conn
+	dialect        Dialect
+	releaseTimeout time.Duration
+	once           sync.Once
+	released       chan struct{}
+	exc            exception.Exception
+}
+
+// acquireLock acquire advisory lock using MySQL GET_LOCK or Postgres pg_try_advisory_lock.
+// MySQL lock name is prefixed with the current database so the lock is scoped to the database the same as Postgres advisory lock.
+// Lock which is not acquired before the timeout is returned as exception.Conflict, negative timeout wait until the kontext is cancelled.
+// Lock is released automatically when the kontext is cancelled.
+func acquireLock(ktx kontext.Context, database *sql.DB, dialect Dialect, name string, timeout, releaseTimeout time.Duration) (Lock, exception.Exception) {
+	if dialect != MySQL && dialect != Postgres {
+		return nil, exception.Throw(fmt.Errorf("%w by %s dialect", ErrLockNotSupported, dialect.Name()), exception.WithType(exception.BadInput))
+	}
+
+	conn, err := database.Conn(ktx.Ctx())
+	if err != nil {
+		return nil, dialect.Translate(err)
+	}
+
+	lock := &advisoryLock{name: name, scoped: name, conn: conn, dialect: dialect, releaseTimeout: releaseTimeout, released: make(chan struct{})}
+
+	if dialect == MySQL {
+		if exc := lock.scope(ktx.Ctx()); exc != nil {
+			_ = conn.Close()
+			return nil, exc
+		}
+	}
+
+	acquired, exc := lock.acquire(ktx.Ctx(), timeout)
+	if exc != nil || !acquired {
+		lock.discard()
+
+		if exc == nil {
+			exc = exception.Throw(fmt.Errorf("timeout acquiring lock %s after %s", name, timeout), exception.WithType(exception.Conflict), exception.WithTitle("lock timeout"))
+		}
+
+		return nil, exc
+	}
+
+	go lock.watch(ktx)
+
+	return lock, nil
+}
+
+// Name of the lock
+func (l *advisoryLock) Name() string {
+	return l.name
+}
+
+// Release the lock and return the pinned connection into the pool, release is not cancelled by the kontext but bounded by WithLockReleaseTimeout.
+// When the lock can not be released the connection is discarded instead so the database release it when the session is closed.
+func (l *advisoryLock) Release(ktx kontext.Context) exception.Exception {
+	l.once.Do(func() {
+		defer close(l.released)
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ktx.Ctx()), l.releaseTimeout)
+		defer cancel()
+
+		l.exc = l.release(ctx)
+		if l.exc != nil {
+			l.discard()
+			return
+		}
+
+		_ = l.conn.Close()
+	})
+
+	return l.exc
+}
+
+func (l *advisoryLock) watch(ktx kontext.Context) {
+	select {
+	case <-ktx.Ctx().Done():
+		_ = l.Release(ktx)
+	case <-l.released:
+	}
+}
+
+// scope prefix MySQL lock name with the current database, GET_LOCK name is shared by every database in the server
+func (l *advisoryLock) scope(ctx context.Context) exception.Exception {
+	var database sql.NullString
+	if err := l.conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		return l.dialect.Translate(err)
+	}
+
+	if database.String != "" {
+		l.scoped = database.String + "." + l.name
+	}
+
+	if len(l.scoped) > 64 {
+		return exception.Throw(fmt.Errorf("lock name %s exceed 64 characters", l.scoped), exception.WithType(exception.BadInput))
+	}
+
+	return nil
+}
+
+func (l *advisoryLock) acquire(ctx context.Context, timeout time.Duration) (bool, exception.Exception) {
+	if l.dialect == MySQL {
+		seconds := -1
+		if timeout >= 0 {
+			seconds = int(math.Ceil(timeout.Seconds()))
+		}
+
+		var acquired sql.NullInt64
+		if err := l.conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.scoped, seconds).Scan(&acquired); err != nil {
+			return false, l.dialect.Translate(err)
+		}
+
+		if !acquired.Valid {
+			return false, exception.Throw(fmt.Errorf("failed acquiring lock %s", l.name))
+		}
+
+		return acquired.Int64 == 1, nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key()).Scan(&acquired); err != nil {
+			return false, l.dialect.Translate(err)
+		}
+
+		if acquired {
+			return true, nil
+		}
+
+		wait := lockPollInterval
+		if timeout >= 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return false, nil
+			}
+
+			if remaining < wait {
+				wait = remaining
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, l.dialect.Translate(ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (l *advisoryLock) release(ctx context.Context) exception.Exception {
+	var released bool
+	var err error
+
+	if l.dialect == MySQL {
+		var result sql.NullInt64
+		err = l.conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", l.scoped).Scan(&result)
+		released = result.Valid && result.Int64 == 1
+	} else {
+		err = l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key()).Scan(&released)
+	}
+
+	if err != nil {
+		return l.dialect.Translate(err)
+	}
+
+	if !released {
+		return exception.Throw(fmt.Errorf("lock %s is not held by the connection", l.name))
+	}
+
+	return nil
+}
+
+// discard close pinned connection instead of returning it into the pool
+func (l *advisoryLock) discard() {
+	_ = l.conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+	_ = l.conn.Close()
+}
+
+// key of postgres advisory lock hashed from the name
+func (l *advisoryLock) key() int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(l.name))
+
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,151 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	newMock := func(t *testing.T) (sqlmock.Sqlmock, func(opts ...db.Option) db.DB) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		t.Cleanup(func() { sqldb.Close() })
+
+		return mockDB, func(opts ...db.Option) db.DB { return db.Adapt(sqldb, opts...) }
+	}
+
+	expectDatabase := func(mockDB sqlmock.Sqlmock) {
+		mockDB.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"database"}).AddRow("app"))
+	}
+
+	t.Run("When the lock is acquired it will be held until released", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WithArgs("app.daily-report", 2).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+		mockDB.ExpectQuery(`SELECT RELEASE_LOCK\(\?\)`).WithArgs("app.daily-report").WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+
+		lock, exc := adapt().Lock(ktx, "daily-report", 1500*time.Millisecond)
+		assert.Nil(t, exc)
+		assert.Equal(t, "daily-report", lock.Name())
+		assert.Nil(t, lock.Release(ktx))
+		assert.Nil(t, lock.Release(ktx))
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When the lock is held by another process until timeout it will return conflict exception", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WithArgs("app.daily-report", 0).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+		lock, exc := adapt().Lock(ktx, "daily-report", 0)
+		assert.Nil(t, lock)
+		assert.Equal(t, exception.Conflict, exc.Type())
+		assert.Equal(t, "lock timeout", exc.Title())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When acquiring the lock failed it will return the failure", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WithArgs("app.daily-report", -1).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(nil))
+
+		_, exc := adapt().Lock(ktx, "daily-report", -1)
+		assert.Equal(t, exception.Unexpected, exc.Type())
+
+		mockDB, adapt = newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WillReturnError(errors.New("connection reset"))
+
+		_, exc = adapt().Lock(ktx, "daily-report", time.Second)
+		assert.Equal(t, exception.Unexpected, exc.Type())
+		assert.NotEqual(t, exception.Conflict, exc.Type())
+	})
+
+	t.Run("When the kontext is cancelled it will release the lock automatically", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+		mockDB.ExpectQuery(`SELECT RELEASE_LOCK\(\?\)`).WithArgs("app.daily-report").WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancellable := kontext.Fabricate(kontext.WithDefaultContext(ctx))
+
+		_, exc := adapt().Lock(cancellable, "daily-report", time.Second)
+		assert.Nil(t, exc)
+
+		cancel()
+		assert.Eventually(t, func() bool { return mockDB.ExpectationsWereMet() == nil }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("When the lock is no longer held it will return exception on release", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+		mockDB.ExpectQuery(`SELECT RELEASE_LOCK\(\?\)`).WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(0))
+
+		lock, exc := adapt().Lock(ktx, "daily-report", time.Second)
+		assert.Nil(t, exc)
+		assert.NotNil(t, lock.Release(ktx))
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When releasing the lock hangs it will return exception after the release timeout", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+		mockDB.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+		mockDB.ExpectQuery(`SELECT RELEASE_LOCK\(\?\)`).WillDelayFor(time.Minute).WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+
+		lock, exc := adapt(db.WithLockReleaseTimeout(50*time.Millisecond)).Lock(ktx, "daily-report", time.Second)
+		assert.Nil(t, exc)
+
+		start := time.Now()
+		assert.NotNil(t, lock.Release(ktx))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("When using postgres it will poll advisory lock until acquired", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		mockDB.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(false))
+		mockDB.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+		mockDB.ExpectQuery(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(true))
+
+		lock, exc := adapt(db.WithDialect(db.Postgres)).Lock(ktx, "daily-report", time.Second)
+		assert.Nil(t, exc)
+		assert.Nil(t, lock.Release(ktx))
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+
+		mockDB, adapt = newMock(t)
+		mockDB.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(false))
+
+		_, exc = adapt(db.WithDialect(db.Postgres)).Lock(ktx, "daily-report", 0)
+		assert.Equal(t, exception.Conflict, exc.Type())
+	})
+
+	t.Run("When the lock is invalid it will return bad input exception", func(t *testing.T) {
+		mockDB, adapt := newMock(t)
+		expectDatabase(mockDB)
+
+		_, exc := adapt().Lock(ktx, strings.Repeat("a", 61), time.Second)
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.False(t, errors.Is(exc, db.ErrLockNotSupported))
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+
+		_, exc = adapt(db.WithDialect(sqlite.Dialect)).Lock(ktx, "daily-report", time.Second)
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.True(t, errors.Is(exc, db.ErrLockNotSupported))
+	})
+}
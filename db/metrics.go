@@ -0,0 +1,212 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// DefaultMetricsBuckets is latency histogram buckets in seconds used when no buckets specified
+var DefaultMetricsBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// MetricsConfig carry metrics collector config
+type MetricsConfig struct {
+	namespace string
+	buckets   []float64
+}
+
+// MetricsOption when fabricating metrics collector
+type MetricsOption func(*MetricsConfig)
+
+// WithMetricsNamespace set prefix of exported metric name, default to "db"
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(c *MetricsConfig) {
+		c.namespace = namespace
+	}
+}
+
+// WithMetricsBuckets set latency histogram buckets in seconds, default to DefaultMetricsBuckets
+func WithMetricsBuckets(buckets ...float64) MetricsOption {
+	return func(c *MetricsConfig) {
+		c.buckets = buckets
+	}
+}
+
+// MetricSeries is aggregated metric of single queryKey, transactionKey or lock name
+type MetricSeries struct {
+	// Kind is either "query", "transaction" or "lock"
+	Kind string
+	Key  string
+
+	Count  uint64
+	Errors map[exception.Type]uint64
+
+	// BucketCounts is non cumulative count of each bucket, the last element is count of observation bigger than the biggest bucket
+	BucketCounts []uint64
+	DurationSum  float64
+}
+
+type metricKey struct {
+	kind string
+	key  string
+}
+
+// Metrics is an Analyzer which aggregate count, error count by exception type and latency histogram per queryKey, transactionKey and lock name
+type Metrics struct {
+	config MetricsConfig
+
+	mutex  sync.Mutex
+	series map[metricKey]*MetricSeries
+}
+
+// FabricateMetrics fabricate metrics collector
+func FabricateMetrics(opts ...MetricsOption) *Metrics {
+	var config MetricsConfig
+
+	// Default value
+	config.namespace = "db"
+	config.buckets = DefaultMetricsBuckets
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	config.buckets = append([]float64(nil), config.buckets...)
+	sort.Float64s(config.buckets)
+
+	return &Metrics{config: config, series: map[metricKey]*MetricSeries{}}
+}
+
+// WithMetrics adapt connection with metrics collector
+func WithMetrics(metrics *Metrics) Option {
+	return WithAnalyzer(metrics)
+}
+
+// Before do nothing, metrics only collected after the execution
+func (m *Metrics) Before(ktx kontext.Context, info QueryInfo) {}
+
+// After collect metrics of executed query, transaction or lock
+func (m *Metrics) After(ktx kontext.Context, info QueryInfo) {
+	key := metricKey{kind: "query", key: info.Key}
+	switch info.Function {
+	case "Transaction":
+		key.kind = "transaction"
+	case "Lock":
+		key.kind = "lock"
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	series, ok := m.series[key]
+	if !ok {
+		series = &MetricSeries{
+			Kind:         key.kind,
+			Key:          key.key,
+			Errors:       map[exception.Type]uint64{},
+			BucketCounts: make([]uint64, len(m.config.buckets)+1),
+		}
+		m.series[key] = series
+	}
+
+	seconds := info.Duration.Seconds()
+
+	series.Count++
+	series.DurationSum += seconds
+	series.BucketCounts[sort.SearchFloat64s(m.config.buckets, seconds)]++
+
+	if info.Exception != nil {
+		series.Errors[info.Exception.Type()]++
+	}
+}
+
+// Snapshot return copy of all collected series sorted by kind and key
+func (m *Metrics) Snapshot() []MetricSeries {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	snapshot := make([]MetricSeries, 0, len(m.series))
+	for _, series := range m.series {
+		copied := *series
+		copied.Errors = make(map[exception.Type]uint64, len(series.Errors))
+		for exceptionType, count := range series.Errors {
+			copied.Errors[exceptionType] = count
+		}
+		copied.BucketCounts = append([]uint64(nil), series.BucketCounts...)
+		snapshot = append(snapshot, copied)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Kind != snapshot[j].Kind {
+			return snapshot[i].Kind < snapshot[j].Kind
+		}
+		return snapshot[i].Key < snapshot[j].Key
+	})
+
+	return snapshot
+}
+
+// WritePrometheus write collected metrics in prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	buffer := bufio.NewWriter(w)
+
+	total := m.config.namespace + "_executions_total"
+	fmt.Fprintf(buffer, "# HELP %s Total of executed query, transaction or lock.\n# TYPE %s counter\n", total, total)
+	for _, series := range snapshot {
+		fmt.Fprintf(buffer, "%s{%s} %d\n", total, metricLabels(series), series.Count)
+	}
+
+	errorsTotal := m.config.namespace + "_errors_total"
+	fmt.Fprintf(buffer, "# HELP %s Total of failed query, transaction or lock by exception type.\n# TYPE %s counter\n", errorsTotal, errorsTotal)
+	for _, series := range snapshot {
+		exceptionTypes := make([]exception.Type, 0, len(series.Errors))
+		for exceptionType := range series.Errors {
+			exceptionTypes = append(exceptionTypes, exceptionType)
+		}
+		sort.Slice(exceptionTypes, func(i, j int) bool { return exceptionTypes[i] < exceptionTypes[j] })
+
+		for _, exceptionType := range exceptionTypes {
+			fmt.Fprintf(buffer, "%s{%s,type=\"%s\"} %d\n", errorsTotal, metricLabels(series), escapeLabelValue(exceptionType.String()), series.Errors[exceptionType])
+		}
+	}
+
+	duration := m.config.namespace + "_duration_seconds"
+	fmt.Fprintf(buffer, "# HELP %s Latency of executed query, transaction or lock.\n# TYPE %s histogram\n", duration, duration)
+	for _, series := range snapshot {
+		var cumulative uint64
+		for i, bucket := range m.config.buckets {
+			cumulative += series.BucketCounts[i]
+			fmt.Fprintf(buffer, "%s_bucket{%s,le=\"%s\"} %d\n", duration, metricLabels(series), strconv.FormatFloat(bucket, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(buffer, "%s_bucket{%s,le=\"+Inf\"} %d\n", duration, metricLabels(series), series.Count)
+		fmt.Fprintf(buffer, "%s_sum{%s} %s\n", duration, metricLabels(series), strconv.FormatFloat(series.DurationSum, 'g', -1, 64))
+		fmt.Fprintf(buffer, "%s_count{%s} %d\n", duration, metricLabels(series), series.Count)
+	}
+
+	return buffer.Flush()
+}
+
+// ServeHTTP expose collected metrics so it can be scraped by prometheus
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+func metricLabels(series MetricSeries) string {
+	return fmt.Sprintf("kind=\"%s\",key=\"%s\"", series.Kind, escapeLabelValue(series.Key))
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
@@ -0,0 +1,107 @@
+package db_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	sqldb, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer sqldb.Close()
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`insert into users`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectExec(`insert into users`).WillReturnError(errors.New("unexpected error"))
+	mockDB.ExpectQuery(`select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	metrics := db.FabricateMetrics(db.WithMetricsNamespace("app_db"), db.WithMetricsBuckets(60))
+	sql := db.Adapt(sqldb, db.WithMetrics(metrics))
+
+	assert.Nil(t, sql.Transaction(ktx, "create-user", func(tx db.TX) exception.Exception {
+		_, exc := tx.ExecContext(ktx, "users.insert", "insert into users")
+		return exc
+	}))
+	_, exc := sql.ExecContext(ktx, "users.insert", "insert into users")
+	assert.NotNil(t, exc)
+	rows, exc := sql.QueryContext(ktx, "users.list", "select id from users")
+	assert.Nil(t, exc)
+	assert.Nil(t, rows.Close())
+	assert.Nil(t, mockDB.ExpectationsWereMet())
+
+	t.Run("Snapshot", func(t *testing.T) {
+		snapshot := metrics.Snapshot()
+		assert.Equal(t, 3, len(snapshot))
+
+		assert.Equal(t, "query", snapshot[0].Kind)
+		assert.Equal(t, "users.insert", snapshot[0].Key)
+		assert.Equal(t, uint64(2), snapshot[0].Count)
+		assert.Equal(t, map[exception.Type]uint64{exception.Unexpected: 1}, snapshot[0].Errors)
+		assert.Equal(t, []uint64{2, 0}, snapshot[0].BucketCounts)
+
+		assert.Equal(t, "query", snapshot[1].Kind)
+		assert.Equal(t, "users.list", snapshot[1].Key)
+		assert.Equal(t, uint64(1), snapshot[1].Count)
+
+		assert.Equal(t, "transaction", snapshot[2].Kind)
+		assert.Equal(t, "create-user", snapshot[2].Key)
+		assert.Equal(t, uint64(1), snapshot[2].Count)
+	})
+
+	t.Run("When lock is acquired it will be collected as lock kind", func(t *testing.T) {
+		lockDB, lockMock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer lockDB.Close()
+
+		lockMock.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"database"}).AddRow(nil))
+		lockMock.ExpectQuery(`SELECT GET_LOCK`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+		lockMetrics := db.FabricateMetrics()
+		_, exc := db.Adapt(lockDB, db.WithMetrics(lockMetrics)).Lock(ktx, "daily-report", 0)
+		assert.Equal(t, exception.Conflict, exc.Type())
+
+		snapshot := lockMetrics.Snapshot()
+		if assert.Equal(t, 1, len(snapshot)) {
+			assert.Equal(t, "lock", snapshot[0].Kind)
+			assert.Equal(t, "daily-report", snapshot[0].Key)
+			assert.Equal(t, map[exception.Type]uint64{exception.Conflict: 1}, snapshot[0].Errors)
+		}
+	})
+
+	t.Run("WritePrometheus", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		assert.Nil(t, metrics.WritePrometheus(buffer))
+
+		output := buffer.String()
+		assert.Contains(t, output, "# TYPE app_db_executions_total counter\n")
+		assert.Contains(t, output, `app_db_executions_total{kind="query",key="users.insert"} 2`)
+		assert.Contains(t, output, `app_db_errors_total{kind="query",key="users.insert",type="unexpected"} 1`)
+		assert.Contains(t, output, "# TYPE app_db_duration_seconds histogram\n")
+		assert.Contains(t, output, `app_db_duration_seconds_bucket{kind="transaction",key="create-user",le="60"} 1`)
+		assert.Contains(t, output, `app_db_duration_seconds_bucket{kind="transaction",key="create-user",le="+Inf"} 1`)
+		assert.Contains(t, output, `app_db_duration_seconds_count{kind="query",key="users.list"} 1`)
+	})
+
+	t.Run("ServeHTTP", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, 200, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, recorder.Body.String(), "app_db_executions_total")
+	})
+}
@@ -0,0 +1,142 @@
+package migration
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kodefluence/monorepo/command"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Command is migrate command scaffold, inject it using command.InjectCommand
+type Command struct {
+	migrator *Migrator
+}
+
+// FabricateCommand fabricate migrate command of the migrator
+func FabricateCommand(migrator *Migrator) command.Scaffold {
+	return &Command{migrator: migrator}
+}
+
+// Use of migrate command
+func (c *Command) Use() string {
+	return "migrate"
+}
+
+// Example of migrate command
+func (c *Command) Example() string {
+	return "migrate up [steps]\nmigrate down [steps]\nmigrate status\nmigrate create [name]\nmigrate force [version] [applied|pending]"
+}
+
+// Short description of migrate command
+func (c *Command) Short() string {
+	return "Apply, revert, list, create or force database migrations"
+}
+
+// Run migrate command, the result and exception is written into configured output.
+// Failed command exit with status 1 so CI and deploy scripts can detect it, see WithExit.
+func (c *Command) Run(args []string) {
+	output := c.migrator.config.output
+
+	exc := c.run(kontext.Fabricate(), args)
+	if exc == nil {
+		return
+	}
+
+	if exc.Title() != "" {
+		fmt.Fprintf(output, "error: %s: %s\n", exc.Title(), exc.Error())
+	} else {
+		fmt.Fprintf(output, "error: %s\n", exc.Error())
+	}
+
+	c.migrator.config.exit(1)
+}
+
+func (c *Command) run(ktx kontext.Context, args []string) exception.Exception {
+	output := c.migrator.config.output
+
+	if len(args) == 0 {
+		return exception.Throw(fmt.Errorf("expected one of up, down, status, create or force"), exception.WithType(exception.BadInput), exception.WithTitle("missing migrate subcommand"))
+	}
+
+	steps := 0
+	if len(args) > 1 && (args[0] == "up" || args[0] == "down") {
+		var err error
+		if steps, err = strconv.Atoi(args[1]); err != nil {
+			return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid steps"))
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		migrations, exc := c.migrator.Up(ktx, steps)
+		for _, migration := range migrations {
+			fmt.Fprintf(output, "applied %d_%s\n", migration.Version, migration.Name)
+		}
+
+		if exc == nil && len(migrations) == 0 {
+			fmt.Fprintln(output, "no pending migration")
+		}
+
+		return exc
+	case "down":
+		migrations, exc := c.migrator.Down(ktx, steps)
+		for _, migration := range migrations {
+			fmt.Fprintf(output, "reverted %d_%s\n", migration.Version, migration.Name)
+		}
+
+		if exc == nil && len(migrations) == 0 {
+			fmt.Fprintln(output, "no applied migration")
+		}
+
+		return exc
+	case "status":
+		statuses, exc := c.migrator.Status(ktx)
+		if exc != nil {
+			return exc
+		}
+
+		for _, status := range statuses {
+			switch {
+			case status.Dirty:
+				fmt.Fprintf(output, "dirty %d_%s\n", status.Version, status.Name)
+			case status.Applied:
+				fmt.Fprintf(output, "applied %d_%s at %s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+			default:
+				fmt.Fprintf(output, "pending %d_%s\n", status.Version, status.Name)
+			}
+		}
+
+		return nil
+	case "create":
+		if len(args) < 2 {
+			return exception.Throw(fmt.Errorf("migration name is required"), exception.WithType(exception.BadInput), exception.WithTitle("missing migration name"))
+		}
+
+		paths, exc := c.migrator.Create(args[1])
+		for _, path := range paths {
+			fmt.Fprintf(output, "created %s\n", path)
+		}
+
+		return exc
+	case "force":
+		if len(args) < 3 || (args[2] != "applied" && args[2] != "pending") {
+			return exception.Throw(fmt.Errorf("expected migrate force [version] [applied|pending]"), exception.WithType(exception.BadInput), exception.WithTitle("missing forced version or state"))
+		}
+
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid version"))
+		}
+
+		if exc := c.migrator.Force(ktx, version, args[2] == "applied"); exc != nil {
+			return exc
+		}
+
+		fmt.Fprintf(output, "forced %d as %s\n", version, args[2])
+		return nil
+	default:
+		return exception.Throw(fmt.Errorf("expected one of up, down, status, create or force, got %s", args[0]), exception.WithType(exception.BadInput), exception.WithTitle("unknown migrate subcommand"))
+	}
+}
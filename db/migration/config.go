@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"io"
+	"time"
+)
+
+// Config carry migrator config
+type Config struct {
+	table      string
+	directory  string
+	output     io.Writer
+	lockExpiry time.Duration
+	exit       func(code int)
+}
+
+// Option of migrator
+type Option func(*Config)
+
+// WithTable set table used to record applied versions, default to "schema_migrations".
+// It also name the advisory lock and the "<table>_lock" lock table used by dialect without advisory lock.
+func WithTable(table string) Option {
+	return func(c *Config) {
+		c.table = table
+	}
+}
+
+// WithLockExpiry set how long lock row is held before it is considered abandoned by killed process, default to 10 minutes.
+// It is only used by dialect without advisory lock such as SQLite, the lock row is refreshed after each applied or reverted migration.
+func WithLockExpiry(lockExpiry time.Duration) Option {
+	return func(c *Config) {
+		c.lockExpiry = lockExpiry
+	}
+}
+
+// WithDirectory set directory where migrate create write new migration files, default to "migrations"
+func WithDirectory(directory string) Option {
+	return func(c *Config) {
+		c.directory = directory
+	}
+}
+
+// WithOutput set writer of migrate command output, default to os.Stdout
+func WithOutput(output io.Writer) Option {
+	return func(c *Config) {
+		c.output = output
+	}
+}
+
+// WithExit set function called with non-zero code when migrate command failed, default to os.Exit
+func WithExit(exit func(code int)) Option {
+	return func(c *Config) {
+		c.exit = exit
+	}
+}
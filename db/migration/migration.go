@@ -0,0 +1,201 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Migration is single versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	HasDown bool
+}
+
+var filePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Dir return migration source from directory in the filesystem
+func Dir(directory string) fs.FS {
+	return os.DirFS(directory)
+}
+
+// Load read migration files from the root of source ordered by version.
+// File is named "<version>_<name>.up.sql" and "<version>_<name>.down.sql", down file is optional. Other files are ignored.
+// Each file is split into statements by semicolon, file containing "-- +migrate single" line is executed as one statement instead,
+// use it for MySQL trigger and procedure whose BEGIN ... END body contain semicolons.
+// Use fs.Sub to load migrations from sub directory of embed.FS.
+func Load(source fs.FS) ([]Migration, exception.Exception) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, exception.Throw(err, exception.WithType(exception.BadInput))
+	}
+
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		matches := filePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, exception.Throw(err, exception.WithType(exception.BadInput))
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, exception.Throw(err)
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = migration
+		}
+
+		if migration.Name != matches[2] {
+			return nil, exception.Throw(fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, matches[2]), exception.WithType(exception.BadInput))
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+			migration.HasDown = true
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, exception.Throw(fmt.Errorf("migration %d_%s does not have up file", migration.Version, migration.Name), exception.WithType(exception.BadInput))
+		}
+
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// singleStatement is directive line making the whole script executed as one statement, used for body which can not be split
+// such as MySQL trigger and procedure with BEGIN ... END block
+const singleStatement = "-- +migrate single"
+
+// statements split sql script by semicolon, semicolon inside quoted text, dollar quoted text and comment is ignored.
+// Backslash escape the next character inside quoted text, comment is dropped except MySQL executable comment "/*! ... */".
+// Script containing singleStatement directive line is returned as it is.
+func statements(script string) []string {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == singleStatement {
+			return []string{strings.TrimSpace(script)}
+		}
+	}
+
+	var result []string
+	var builder strings.Builder
+	var quote rune
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+
+		switch {
+		case quote != 0:
+			if char == '\\' && quote != '`' && i+1 < len(runes) {
+				builder.WriteRune(char)
+				i++
+				char = runes[i]
+			} else if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '$' && dollarTag(runes[i:]) != "":
+			tag := dollarTag(runes[i:])
+			end := indexOf(runes, i+len(tag), []rune(tag))
+			if end < 0 {
+				end = len(runes)
+			} else {
+				end += len(tag)
+			}
+
+			builder.WriteString(string(runes[i:end]))
+			i = end - 1
+			continue
+		case char == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+			continue
+		case char == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := indexOf(runes, i+2, []rune("*/"))
+			if end < 0 {
+				end = len(runes)
+			} else {
+				end += 2
+			}
+
+			if i+2 < len(runes) && runes[i+2] == '!' {
+				builder.WriteString(string(runes[i:end]))
+			}
+
+			i = end - 1
+			continue
+		case char == ';':
+			if statement := strings.TrimSpace(builder.String()); statement != "" {
+				result = append(result, statement)
+			}
+			builder.Reset()
+			continue
+		}
+
+		builder.WriteRune(char)
+	}
+
+	if statement := strings.TrimSpace(builder.String()); statement != "" {
+		result = append(result, statement)
+	}
+
+	return result
+}
+
+// dollarTag return opening tag of Postgres dollar quoted text such as "$$" or "$body$", it return empty string when runes is not started by one.
+// Positional parameter such as "$1" is not a tag since tag can not start with digit.
+func dollarTag(runes []rune) string {
+	for i := 1; i < len(runes); i++ {
+		switch char := runes[i]; {
+		case char == '$':
+			return string(runes[:i+1])
+		case char == '_' || unicode.IsLetter(char) || (i > 1 && unicode.IsDigit(char)):
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// indexOf return index of the first target in runes starting from start, or -1 when it is not found
+func indexOf(runes []rune, start int, target []rune) int {
+	for i := start; i+len(target) <= len(runes); i++ {
+		if string(runes[i:i+len(target)]) == string(target) {
+			return i
+		}
+	}
+
+	return -1
+}
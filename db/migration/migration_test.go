@@ -0,0 +1,363 @@
+package migration_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/command"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/fake"
+	"github.com/kodefluence/monorepo/db/migration"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+var source = fstest.MapFS{
+	"1_create_users.up.sql":        {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL); -- users; table\nINSERT INTO users (name) VALUES ('semicolon; inside');")},
+	"1_create_users.down.sql":      {Data: []byte("DROP TABLE users;")},
+	"2_create_posts.up.sql":        {Data: []byte("/* posts; table */ CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL);")},
+	"2_create_posts.down.sql":      {Data: []byte("DROP TABLE posts;")},
+	"3_add_posts_title.up.sql":     {Data: []byte("ALTER TABLE posts ADD COLUMN title TEXT;")},
+	"README.md":                    {Data: []byte("ignored")},
+	"4_broken.up.sql":              {Data: []byte("CREATE TABLE comments (id INTEGER PRIMARY KEY); ALTER TABLE unknown ADD COLUMN title TEXT;")},
+	"4_broken.down.sql":            {Data: []byte("DROP TABLE comments;")},
+	"nested/5_ignored.up.sql":      {Data: []byte("ignored")},
+	"nested/5_ignored.down.sql":    {Data: []byte("ignored")},
+	"3_add_posts_title.up.sql.bak": {Data: []byte("ignored")},
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("When files is valid it will load migrations ordered by version", func(t *testing.T) {
+		migrations, exc := migration.Load(source)
+		assert.Nil(t, exc)
+		assert.Equal(t, 4, len(migrations))
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "create_users", migrations[0].Name)
+		assert.True(t, migrations[0].HasDown)
+		assert.False(t, migrations[2].HasDown)
+	})
+
+	t.Run("When up file is missing it will return bad input exception", func(t *testing.T) {
+		_, exc := migration.Load(fstest.MapFS{"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")}})
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+
+	t.Run("When version is used by different names it will return bad input exception", func(t *testing.T) {
+		_, exc := migration.Load(fstest.MapFS{
+			"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INTEGER);")},
+			"1_create_posts.up.sql": {Data: []byte("CREATE TABLE posts (id INTEGER);")},
+		})
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+}
+
+func TestStatements(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	split := func(script string) []string {
+		fakeDB := fake.Fabricate()
+
+		migrator, exc := migration.Fabricate(fakeDB, fstest.MapFS{"1_split.up.sql": {Data: []byte(script)}})
+		assert.Nil(t, exc)
+
+		_, exc = migrator.Up(ktx, 0)
+		assert.Nil(t, exc)
+
+		var statements []string
+		for _, query := range fakeDB.Queries("migration.up") {
+			statements = append(statements, query.SQL)
+		}
+
+		return statements
+	}
+
+	t.Run("When function body is dollar quoted it will not be split", func(t *testing.T) {
+		statements := split("CREATE FUNCTION touch() RETURNS trigger AS $body$ BEGIN NEW.updated_at = now(); RETURN NEW; END; $body$ LANGUAGE plpgsql; SELECT $$a;b$$, $1;")
+		assert.Equal(t, []string{
+			"CREATE FUNCTION touch() RETURNS trigger AS $body$ BEGIN NEW.updated_at = now(); RETURN NEW; END; $body$ LANGUAGE plpgsql",
+			"SELECT $$a;b$$, $1",
+		}, statements)
+	})
+
+	t.Run("When quoted text has backslash escaped quote it will stay inside the text", func(t *testing.T) {
+		statements := split(`INSERT INTO notes (body) VALUES ('it\'s; fine'); INSERT INTO notes (body) VALUES ("say \"hi;\"");`)
+		assert.Equal(t, []string{
+			`INSERT INTO notes (body) VALUES ('it\'s; fine')`,
+			`INSERT INTO notes (body) VALUES ("say \"hi;\"")`,
+		}, statements)
+	})
+
+	t.Run("When script has MySQL executable comment it will be kept", func(t *testing.T) {
+		statements := split("/*!40101 SET NAMES utf8mb4 */; /* dropped; */ CREATE TABLE users (id INT) /*!50100 ENGINE=InnoDB */;")
+		assert.Equal(t, []string{
+			"/*!40101 SET NAMES utf8mb4 */",
+			"CREATE TABLE users (id INT) /*!50100 ENGINE=InnoDB */",
+		}, statements)
+	})
+
+	t.Run("When script has single statement directive it will be executed as one statement", func(t *testing.T) {
+		script := "-- +migrate single\nCREATE TRIGGER touch BEFORE UPDATE ON users FOR EACH ROW BEGIN SET NEW.updated_at = NOW(); SET NEW.version = OLD.version + 1; END"
+		assert.Equal(t, []string{script}, split(script))
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	sqldb, exc := sqlite.Fabricate("migration-test", db.Config{Name: sqlite.Memory})
+	assert.Nil(t, exc)
+	defer sqldb.Eject().Close()
+
+	migrations := fstest.MapFS{}
+	for name, file := range source {
+		if name[0] != '4' {
+			migrations[name] = file
+		}
+	}
+
+	migrator, exc := migration.Fabricate(sqldb, migrations)
+	assert.Nil(t, exc)
+
+	t.Run("When migrating up with steps it will only apply that many pending migrations", func(t *testing.T) {
+		applied, exc := migrator.Up(ktx, 1)
+		assert.Nil(t, exc)
+		assert.Equal(t, 1, len(applied))
+
+		var name string
+		assert.Nil(t, sqldb.QueryRowContext(ktx, "find-user", "SELECT name FROM users").Scan(&name))
+		assert.Equal(t, "semicolon; inside", name)
+	})
+
+	t.Run("When migrating up without steps it will apply every pending migrations", func(t *testing.T) {
+		applied, exc := migrator.Up(ktx, 0)
+		assert.Nil(t, exc)
+		assert.Equal(t, 2, len(applied))
+
+		statuses, exc := migrator.Status(ktx)
+		assert.Nil(t, exc)
+		for _, status := range statuses {
+			assert.True(t, status.Applied)
+			assert.False(t, status.AppliedAt.IsZero())
+		}
+	})
+
+	t.Run("When migration does not have down file it will return bad input exception", func(t *testing.T) {
+		reverted, exc := migrator.Down(ktx, 1)
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.Equal(t, 0, len(reverted))
+	})
+
+	t.Run("When migration is locked by another process it will return conflict exception", func(t *testing.T) {
+		_, exc := sqldb.ExecContext(ktx, "lock-migration", "INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, CURRENT_TIMESTAMP)")
+		assert.Nil(t, exc)
+
+		_, exc = migrator.Up(ktx, 0)
+		assert.Equal(t, exception.Conflict, exc.Type())
+
+		_, exc = sqldb.ExecContext(ktx, "unlock-migration", "DELETE FROM schema_migrations_lock")
+		assert.Nil(t, exc)
+	})
+
+	t.Run("When lock row is left by killed process it will be taken over after it expire", func(t *testing.T) {
+		_, exc := sqldb.ExecContext(ktx, "lock-migration", "INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", time.Now().UTC().Add(-time.Hour))
+		assert.Nil(t, exc)
+
+		expiring, exc := migration.Fabricate(sqldb, migrations, migration.WithLockExpiry(time.Minute))
+		assert.Nil(t, exc)
+
+		_, exc = expiring.Up(ktx, 0)
+		assert.Nil(t, exc)
+
+		var count int
+		assert.Nil(t, sqldb.QueryRowContext(ktx, "count-lock", "SELECT COUNT(*) FROM schema_migrations_lock").Scan(&count))
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("When migration failed it will be rolled back and stay dirty until it is forced", func(t *testing.T) {
+		broken, exc := migration.Fabricate(sqldb, source)
+		assert.Nil(t, exc)
+
+		applied, exc := broken.Up(ktx, 0)
+		assert.NotNil(t, exc)
+		assert.Equal(t, 0, len(applied))
+
+		statuses, exc := broken.Status(ktx)
+		assert.Nil(t, exc)
+		assert.False(t, statuses[3].Applied)
+		assert.True(t, statuses[3].Dirty)
+
+		var count int
+		assert.Nil(t, sqldb.QueryRowContext(ktx, "count-lock", "SELECT COUNT(*) FROM schema_migrations_lock").Scan(&count))
+		assert.Equal(t, 0, count)
+
+		_, exc = broken.Up(ktx, 0)
+		assert.Equal(t, "database is dirty", exc.Title())
+
+		_, exc = broken.Down(ktx, 1)
+		assert.Equal(t, "database is dirty", exc.Title())
+
+		assert.Equal(t, exception.NotFound, broken.Force(ktx, 3, true).Type())
+		assert.Nil(t, broken.Force(ktx, 4, false))
+
+		statuses, exc = broken.Status(ktx)
+		assert.Nil(t, exc)
+		assert.False(t, statuses[3].Applied)
+		assert.False(t, statuses[3].Dirty)
+	})
+
+	t.Run("When dialect support advisory lock it will be used instead of lock row", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		locking, exc := migration.Fabricate(fakeDB, migrations)
+		assert.Nil(t, exc)
+
+		lock, exc := fakeDB.Lock(ktx, "schema_migrations", 0)
+		assert.Nil(t, exc)
+
+		_, exc = locking.Up(ktx, 0)
+		assert.Equal(t, "migration is locked by another process", exc.Title())
+		assert.False(t, fakeDB.Executed("migration.lock"))
+
+		assert.Nil(t, lock.Release(ktx))
+
+		_, exc = locking.Up(ktx, 0)
+		assert.Nil(t, exc)
+		assert.False(t, fakeDB.Executed("migration.lock"))
+		assert.False(t, fakeDB.Locked("schema_migrations"))
+	})
+
+	t.Run("When advisory lock failed for other reason it will return the exception instead of using lock row", func(t *testing.T) {
+		sqlDB, mockDB, err := sqlmock.New()
+		assert.Nil(t, err)
+		defer sqlDB.Close()
+
+		mockDB.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mockDB.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"database"}).AddRow(strings.Repeat("a", 50)))
+
+		mysqlMigrator, exc := migration.Fabricate(db.Adapt(sqlDB), migrations)
+		assert.Nil(t, exc)
+
+		_, exc = mysqlMigrator.Up(ktx, 0)
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When migrating down it will revert the latest applied migrations", func(t *testing.T) {
+		reversible, exc := migration.Fabricate(sqldb, fstest.MapFS{
+			"1_create_users.up.sql":   source["1_create_users.up.sql"],
+			"1_create_users.down.sql": source["1_create_users.down.sql"],
+			"2_create_posts.up.sql":   source["2_create_posts.up.sql"],
+			"2_create_posts.down.sql": source["2_create_posts.down.sql"],
+		})
+		assert.Nil(t, exc)
+
+		_, exc = sqldb.ExecContext(ktx, "delete-migration", "DELETE FROM schema_migrations WHERE version = 3")
+		assert.Nil(t, exc)
+
+		reverted, exc := reversible.Down(ktx, 2)
+		assert.Nil(t, exc)
+		assert.Equal(t, 2, len(reverted))
+		assert.Equal(t, int64(2), reverted[0].Version)
+
+		statuses, exc := reversible.Status(ktx)
+		assert.Nil(t, exc)
+		assert.False(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	})
+}
+
+func TestCommand(t *testing.T) {
+	sqldb, exc := sqlite.Fabricate("migration-command-test", db.Config{Name: sqlite.Memory})
+	assert.Nil(t, exc)
+	defer sqldb.Eject().Close()
+
+	directory := filepath.Join(t.TempDir(), "migrations")
+	output := &bytes.Buffer{}
+	code := 0
+	exit := migration.WithExit(func(c int) { code = c })
+
+	migrator, exc := migration.Fabricate(sqldb, migration.Dir(directory), migration.WithDirectory(directory), migration.WithOutput(output), migration.WithTable("migrations"), exit)
+	assert.Nil(t, exc)
+
+	execute := func(args ...string) string {
+		output.Reset()
+		code = 0
+
+		cmd := command.Fabricate()
+		cmd.InjectCommand(migration.FabricateCommand(migrator))
+		cmd.SetArgs(append([]string{"migrate"}, args...))
+		assert.Nil(t, cmd.Execute())
+
+		return output.String()
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.Contains(t, execute("create", "create_users"), "created ")
+
+		files, err := filepath.Glob(filepath.Join(directory, "*_create_users.*.sql"))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(files))
+
+		for _, file := range files {
+			if filepath.Ext(file[:len(file)-4]) == ".up" {
+				assert.Nil(t, os.WriteFile(file, []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);"), 0o644))
+			} else {
+				assert.Nil(t, os.WriteFile(file, []byte("DROP TABLE users;"), 0o644))
+			}
+		}
+
+		assert.Equal(t, 0, code)
+
+		assert.Contains(t, execute("create", "../escape"), "invalid migration name")
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("up", func(t *testing.T) {
+		migrator, exc = migration.Fabricate(sqldb, migration.Dir(directory), migration.WithDirectory(directory), migration.WithOutput(output), migration.WithTable("migrations"), exit)
+		assert.Nil(t, exc)
+
+		assert.Contains(t, execute("status"), "pending ")
+		assert.Contains(t, execute("up"), "applied ")
+		assert.Contains(t, execute("up"), "no pending migration")
+		assert.Contains(t, execute("status"), "applied ")
+		assert.Equal(t, 0, code)
+	})
+
+	t.Run("When migration failed it will exit with non-zero status", func(t *testing.T) {
+		broken, exc := migration.Fabricate(sqldb, fstest.MapFS{"1_broken.up.sql": {Data: []byte("ALTER TABLE unknown ADD COLUMN title TEXT;")}}, migration.WithOutput(output), migration.WithTable("broken_migrations"), exit)
+		assert.Nil(t, exc)
+
+		output.Reset()
+		code = 0
+
+		cmd := command.Fabricate()
+		cmd.InjectCommand(migration.FabricateCommand(broken))
+		cmd.SetArgs([]string{"migrate", "up"})
+		assert.Nil(t, cmd.Execute())
+
+		assert.Contains(t, output.String(), "error: failed applying migration 1_broken")
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		assert.Contains(t, execute("down", "1"), "reverted ")
+		assert.Contains(t, execute("down"), "no applied migration")
+		assert.Contains(t, execute("down", "one"), "invalid steps")
+	})
+
+	t.Run("others", func(t *testing.T) {
+		assert.Contains(t, execute(), "missing migrate subcommand")
+		assert.Contains(t, execute("redo"), "unknown migrate subcommand")
+		assert.Contains(t, execute("create"), "missing migration name")
+		assert.Contains(t, execute("force", "1"), "missing forced version or state")
+		assert.Contains(t, execute("force", "one", "applied"), "invalid version")
+		assert.Contains(t, execute("force", "1", "applied"), "is not dirty")
+	})
+}
@@ -0,0 +1,343 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Status of single migration
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+
+	// Dirty when the migration failed halfway, its schema change may be partially applied since MySQL commit DDL implicitly
+	Dirty bool
+}
+
+// record of migration in tracking table
+type record struct {
+	appliedAt time.Time
+	dirty     bool
+}
+
+// Migrator apply and revert migrations, applied versions is recorded in tracking table
+type Migrator struct {
+	db         db.DB
+	migrations []Migration
+	config     Config
+}
+
+// Fabricate migrator of database with migrations loaded from source, see Load for the file naming.
+// Source directory which does not exist is treated as empty so migrate create can be run in new project.
+func Fabricate(database db.DB, source fs.FS, opts ...Option) (*Migrator, exception.Exception) {
+	var config Config
+
+	// Default value
+	config.table = "schema_migrations"
+	config.directory = "migrations"
+	config.output = os.Stdout
+	config.lockExpiry = 10 * time.Minute
+	config.exit = os.Exit
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	migrations, exc := Load(source)
+	if exc != nil && !errors.Is(exc, fs.ErrNotExist) {
+		return nil, exc
+	}
+
+	return &Migrator{db: database, migrations: migrations, config: config}, nil
+}
+
+// Migrations return every loaded migration ordered by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up apply pending migrations ordered by version, steps limit how many migrations is applied and steps <= 0 apply every pending migration.
+// Each migration is applied in its own transaction, it return migrations that has been applied even when the later one failed.
+// Migration is recorded as dirty before it is applied and cleaned in the same transaction, failed migration stay dirty until it is resolved using Force.
+func (m *Migrator) Up(ktx kontext.Context, steps int) ([]Migration, exception.Exception) {
+	var applied []Migration
+
+	exc := m.locked(ktx, func(records map[int64]record, refresh func() exception.Exception) exception.Exception {
+		for _, migration := range m.migrations {
+			if steps > 0 && len(applied) >= steps {
+				break
+			}
+
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+
+			if _, exc := m.db.ExecContext(ktx, "migration.record", m.query("INSERT INTO %s (version, name, applied_at, dirty) VALUES (?, ?, ?, ?)"), migration.Version, migration.Name, time.Now().UTC(), true); exc != nil {
+				return exc
+			}
+
+			exc := m.db.Transaction(ktx, "migration.up", func(tx db.TX) exception.Exception {
+				for _, statement := range statements(migration.Up) {
+					if _, exc := tx.ExecContext(ktx, "migration.up", statement); exc != nil {
+						return exc
+					}
+				}
+
+				_, exc := tx.ExecContext(ktx, "migration.clean", m.query("UPDATE %s SET applied_at = ?, dirty = ? WHERE version = ?"), time.Now().UTC(), false, migration.Version)
+				return exc
+			})
+			if exc != nil {
+				return exception.Throw(exc, exception.WithType(exc.Type()), exception.WithTitle(fmt.Sprintf("failed applying migration %d_%s", migration.Version, migration.Name)), exception.WithDetail(exc.Detail()))
+			}
+
+			applied = append(applied, migration)
+
+			if exc := refresh(); exc != nil {
+				return exc
+			}
+		}
+
+		return nil
+	})
+
+	return applied, exc
+}
+
+// Down revert applied migrations starting from the latest version, steps limit how many migrations is reverted and steps <= 0 is treated as 1.
+// Migration without down file can not be reverted.
+func (m *Migrator) Down(ktx kontext.Context, steps int) ([]Migration, exception.Exception) {
+	var reverted []Migration
+
+	if steps <= 0 {
+		steps = 1
+	}
+
+	exc := m.locked(ktx, func(records map[int64]record, refresh func() exception.Exception) exception.Exception {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := records[migration.Version]; !ok {
+				continue
+			}
+
+			if !migration.HasDown {
+				return exception.Throw(fmt.Errorf("migration %d_%s does not have down file", migration.Version, migration.Name), exception.WithType(exception.BadInput))
+			}
+
+			if _, exc := m.db.ExecContext(ktx, "migration.dirty", m.query("UPDATE %s SET dirty = ? WHERE version = ?"), true, migration.Version); exc != nil {
+				return exc
+			}
+
+			exc := m.db.Transaction(ktx, "migration.down", func(tx db.TX) exception.Exception {
+				for _, statement := range statements(migration.Down) {
+					if _, exc := tx.ExecContext(ktx, "migration.down", statement); exc != nil {
+						return exc
+					}
+				}
+
+				_, exc := tx.ExecContext(ktx, "migration.unrecord", m.query("DELETE FROM %s WHERE version = ?"), migration.Version)
+				return exc
+			})
+			if exc != nil {
+				return exception.Throw(exc, exception.WithType(exc.Type()), exception.WithTitle(fmt.Sprintf("failed reverting migration %d_%s", migration.Version, migration.Name)), exception.WithDetail(exc.Detail()))
+			}
+
+			reverted = append(reverted, migration)
+
+			if exc := refresh(); exc != nil {
+				return exc
+			}
+		}
+
+		return nil
+	})
+
+	return reverted, exc
+}
+
+// Status of every loaded migration ordered by version
+func (m *Migrator) Status(ktx kontext.Context) ([]Status, exception.Exception) {
+	if exc := m.prepare(ktx); exc != nil {
+		return nil, exc
+	}
+
+	records, exc := m.records(ktx)
+	if exc != nil {
+		return nil, exc
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		record, ok := records[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok && !record.dirty, AppliedAt: record.appliedAt, Dirty: record.dirty})
+	}
+
+	return statuses, nil
+}
+
+// Force resolve dirty migration after its schema change is fixed manually.
+// Applied true record the migration as applied, otherwise the record is deleted so the migration is pending again.
+func (m *Migrator) Force(ktx kontext.Context, version int64, applied bool) exception.Exception {
+	if exc := m.prepare(ktx); exc != nil {
+		return exc
+	}
+
+	var result db.Result
+	var exc exception.Exception
+	if applied {
+		result, exc = m.db.ExecContext(ktx, "migration.force", m.query("UPDATE %s SET dirty = ? WHERE version = ? AND dirty = ?"), false, version, true)
+	} else {
+		result, exc = m.db.ExecContext(ktx, "migration.force", m.query("DELETE FROM %s WHERE version = ? AND dirty = ?"), version, true)
+	}
+	if exc != nil {
+		return exc
+	}
+
+	rowsAffected, exc := result.RowsAffected()
+	if exc != nil {
+		return exc
+	}
+
+	if rowsAffected == 0 {
+		return exception.Throw(fmt.Errorf("migration version %d is not dirty", version), exception.WithType(exception.NotFound))
+	}
+
+	return nil
+}
+
+// Create write empty up and down migration files named with current UTC timestamp as the version into configured directory, it return created file paths
+func (m *Migrator) Create(name string) ([]string, exception.Exception) {
+	if !filePattern.MatchString(fmt.Sprintf("0_%s.up.sql", name)) || filepath.Base(name) != name {
+		return nil, exception.Throw(fmt.Errorf("invalid migration name: %s", name), exception.WithType(exception.BadInput))
+	}
+
+	if err := os.MkdirAll(m.config.directory, 0o755); err != nil {
+		return nil, exception.Throw(err)
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(m.config.directory, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("-- %s migration of %s\n", direction, name)), 0o644); err != nil {
+			return nil, exception.Throw(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// prepare create tracking table when it does not exist yet
+func (m *Migrator) prepare(ktx kontext.Context) exception.Exception {
+	_, exc := m.db.ExecContext(ktx, "migration.create_table", m.query("CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL, dirty BOOLEAN NOT NULL DEFAULT FALSE)"))
+	return exc
+}
+
+func (m *Migrator) records(ktx kontext.Context) (map[int64]record, exception.Exception) {
+	rows, exc := m.db.QueryContext(ktx, "migration.applied", m.query("SELECT version, applied_at, dirty FROM %s"))
+	if exc != nil {
+		return nil, exc
+	}
+	defer rows.Close()
+
+	records := map[int64]record{}
+	for rows.Next() {
+		var version int64
+		var record record
+		if exc := rows.Scan(&version, &record.appliedAt, &record.dirty); exc != nil {
+			return nil, exc
+		}
+
+		records[version] = record
+	}
+
+	return records, rows.Err()
+}
+
+// locked run f while holding migration lock, concurrent run fail with exception.Conflict and dirty migration has to be resolved using Force first.
+// The lock is advisory lock when the dialect support it, otherwise it is a single row in lock table which expire after lock expiry, see WithLockExpiry.
+// Refresh passed into f extend the lock row so long running migrations does not lose it.
+func (m *Migrator) locked(ktx kontext.Context, f func(records map[int64]record, refresh func() exception.Exception) exception.Exception) exception.Exception {
+	if exc := m.prepare(ktx); exc != nil {
+		return exc
+	}
+
+	refresh := func() exception.Exception { return nil }
+
+	// Lock name is scoped to the current database by db.Lock, so migrations of other databases in the same server is not blocked
+	lock, exc := m.db.Lock(ktx, m.config.table, 0)
+	switch {
+	case exc == nil:
+		defer func() { _ = lock.Release(ktx) }()
+	case errors.Is(exc, db.ErrLockNotSupported):
+		if exc := m.lockRow(ktx); exc != nil {
+			return exc
+		}
+
+		defer func() {
+			_, _ = m.db.ExecContext(ktx, "migration.unlock", m.query("DELETE FROM %s_lock WHERE id = 1"))
+		}()
+
+		refresh = func() exception.Exception {
+			_, exc := m.db.ExecContext(ktx, "migration.refresh_lock", m.query("UPDATE %s_lock SET locked_at = ? WHERE id = 1"), time.Now().UTC())
+			return exc
+		}
+	case exc.Type() == exception.Conflict:
+		return m.lockConflict(exc)
+	default:
+		return exc
+	}
+
+	records, exc := m.records(ktx)
+	if exc != nil {
+		return exc
+	}
+
+	for version, record := range records {
+		if record.dirty {
+			return exception.Throw(fmt.Errorf("migration version %d is dirty", version), exception.WithType(exception.Conflict), exception.WithTitle("database is dirty"), exception.WithDetail(fmt.Sprintf("fix the schema change of version %d manually then run migrate force %d applied or migrate force %d pending", version, version, version)))
+		}
+	}
+
+	return f(records, refresh)
+}
+
+// lockRow insert single row into lock table, the row left by killed process is taken over after it expire
+func (m *Migrator) lockRow(ktx kontext.Context) exception.Exception {
+	if _, exc := m.db.ExecContext(ktx, "migration.create_lock_table", m.query("CREATE TABLE IF NOT EXISTS %s_lock (id INT NOT NULL PRIMARY KEY, locked_at TIMESTAMP NOT NULL)")); exc != nil {
+		return exc
+	}
+
+	now := time.Now().UTC()
+
+	if _, exc := m.db.ExecContext(ktx, "migration.expire_lock", m.query("DELETE FROM %s_lock WHERE id = 1 AND locked_at < ?"), now.Add(-m.config.lockExpiry)); exc != nil {
+		return exc
+	}
+
+	_, exc := m.db.ExecContext(ktx, "migration.lock", m.query("INSERT INTO %s_lock (id, locked_at) VALUES (1, ?)"), now)
+	if exc != nil && exc.Type() == exception.Duplicated {
+		return m.lockConflict(exc)
+	}
+
+	return exc
+}
+
+func (m *Migrator) lockConflict(exc exception.Exception) exception.Exception {
+	return exception.Throw(exc, exception.WithType(exception.Conflict), exception.WithTitle("migration is locked by another process"), exception.WithDetail(fmt.Sprintf("wait for the other migration to finish, lock row in %s_lock expire after %s", m.config.table, m.config.lockExpiry)))
+}
+
+// query format tracking table into the query and rebind it into the dialect
+func (m *Migrator) query(format string) string {
+	return db.Rebind(m.db.Dialect(), fmt.Sprintf(format, m.config.table))
+}
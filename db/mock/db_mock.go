@@ -7,6 +7,7 @@ package mock
 import (
 	sql "database/sql"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	db "github.com/kodefluence/monorepo/db"
@@ -37,6 +38,20 @@ func (m *MockDB) EXPECT() *MockDBMockRecorder {
 	return m.recorder
 }
 
+// Dialect mocks base method.
+func (m *MockDB) Dialect() db.Dialect {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dialect")
+	ret0, _ := ret[0].(db.Dialect)
+	return ret0
+}
+
+// Dialect indicates an expected call of Dialect.
+func (mr *MockDBMockRecorder) Dialect() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dialect", reflect.TypeOf((*MockDB)(nil).Dialect))
+}
+
 // Eject mocks base method.
 func (m *MockDB) Eject() *sql.DB {
 	m.ctrl.T.Helper()
@@ -71,6 +86,60 @@ func (mr *MockDBMockRecorder) ExecContext(ctx, queryKey, query interface{}, args
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*MockDB)(nil).ExecContext), varargs...)
 }
 
+// ExecNamed mocks base method.
+func (m *MockDB) ExecNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (db.Result, exception.Exception) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecNamed", ctx, queryKey, query, arg)
+	ret0, _ := ret[0].(db.Result)
+	ret1, _ := ret[1].(exception.Exception)
+	return ret0, ret1
+}
+
+// ExecNamed indicates an expected call of ExecNamed.
+func (mr *MockDBMockRecorder) ExecNamed(ctx, queryKey, query, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecNamed", reflect.TypeOf((*MockDB)(nil).ExecNamed), ctx, queryKey, query, arg)
+}
+
+// Lock mocks base method.
+func (m *MockDB) Lock(ktx kontext.Context, name string, timeout time.Duration) (db.Lock, exception.Exception) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ktx, name, timeout)
+	ret0, _ := ret[0].(db.Lock)
+	ret1, _ := ret[1].(exception.Exception)
+	return ret0, ret1
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockDBMockRecorder) Lock(ktx, name, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockDB)(nil).Lock), ktx, name, timeout)
+}
+
+// OnCommit mocks base method.
+func (m *MockDB) OnCommit(ctx kontext.Context, f func(kontext.Context)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnCommit", ctx, f)
+}
+
+// OnCommit indicates an expected call of OnCommit.
+func (mr *MockDBMockRecorder) OnCommit(ctx, f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnCommit", reflect.TypeOf((*MockDB)(nil).OnCommit), ctx, f)
+}
+
+// OnRollback mocks base method.
+func (m *MockDB) OnRollback(f func(kontext.Context)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnRollback", f)
+}
+
+// OnRollback indicates an expected call of OnRollback.
+func (mr *MockDBMockRecorder) OnRollback(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRollback", reflect.TypeOf((*MockDB)(nil).OnRollback), f)
+}
+
 // Ping mocks base method.
 func (m *MockDB) Ping(ktx kontext.Context) exception.Exception {
 	m.ctrl.T.Helper()
@@ -105,6 +174,21 @@ func (mr *MockDBMockRecorder) QueryContext(ctx, queryKey, query interface{}, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*MockDB)(nil).QueryContext), varargs...)
 }
 
+// QueryNamed mocks base method.
+func (m *MockDB) QueryNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (db.Rows, exception.Exception) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryNamed", ctx, queryKey, query, arg)
+	ret0, _ := ret[0].(db.Rows)
+	ret1, _ := ret[1].(exception.Exception)
+	return ret0, ret1
+}
+
+// QueryNamed indicates an expected call of QueryNamed.
+func (mr *MockDBMockRecorder) QueryNamed(ctx, queryKey, query, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryNamed", reflect.TypeOf((*MockDB)(nil).QueryNamed), ctx, queryKey, query, arg)
+}
+
 // QueryRowContext mocks base method.
 func (m *MockDB) QueryRowContext(ctx kontext.Context, queryKey, query string, args ...interface{}) db.Row {
 	m.ctrl.T.Helper()
@@ -124,18 +208,37 @@ func (mr *MockDBMockRecorder) QueryRowContext(ctx, queryKey, query interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*MockDB)(nil).QueryRowContext), varargs...)
 }
 
+// Stats mocks base method.
+func (m *MockDB) Stats() db.PoolStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(db.PoolStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockDBMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDB)(nil).Stats))
+}
+
 // Transaction mocks base method.
-func (m *MockDB) Transaction(ctx kontext.Context, transactionKey string, f func(db.TX) exception.Exception) exception.Exception {
+func (m *MockDB) Transaction(ctx kontext.Context, transactionKey string, f func(db.TX) exception.Exception, opts ...db.TransactionOption) exception.Exception {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Transaction", ctx, transactionKey, f)
+	varargs := []interface{}{ctx, transactionKey, f}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transaction", varargs...)
 	ret0, _ := ret[0].(exception.Exception)
 	return ret0
 }
 
 // Transaction indicates an expected call of Transaction.
-func (mr *MockDBMockRecorder) Transaction(ctx, transactionKey, f interface{}) *gomock.Call {
+func (mr *MockDBMockRecorder) Transaction(ctx, transactionKey, f interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockDB)(nil).Transaction), ctx, transactionKey, f)
+	varargs := append([]interface{}{ctx, transactionKey, f}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockDB)(nil).Transaction), varargs...)
 }
 
 // MockTransactionable is a mock of Transactionable interface.
@@ -162,17 +265,22 @@ func (m *MockTransactionable) EXPECT() *MockTransactionableMockRecorder {
 }
 
 // Transaction mocks base method.
-func (m *MockTransactionable) Transaction(ctx kontext.Context, transactionKey string, f func(db.TX) exception.Exception) exception.Exception {
+func (m *MockTransactionable) Transaction(ctx kontext.Context, transactionKey string, f func(db.TX) exception.Exception, opts ...db.TransactionOption) exception.Exception {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Transaction", ctx, transactionKey, f)
+	varargs := []interface{}{ctx, transactionKey, f}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transaction", varargs...)
 	ret0, _ := ret[0].(exception.Exception)
 	return ret0
 }
 
 // Transaction indicates an expected call of Transaction.
-func (mr *MockTransactionableMockRecorder) Transaction(ctx, transactionKey, f interface{}) *gomock.Call {
+func (mr *MockTransactionableMockRecorder) Transaction(ctx, transactionKey, f interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockTransactionable)(nil).Transaction), ctx, transactionKey, f)
+	varargs := append([]interface{}{ctx, transactionKey, f}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockTransactionable)(nil).Transaction), varargs...)
 }
 
 // MockTX is a mock of TX interface.
@@ -198,6 +306,20 @@ func (m *MockTX) EXPECT() *MockTXMockRecorder {
 	return m.recorder
 }
 
+// Dialect mocks base method.
+func (m *MockTX) Dialect() db.Dialect {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dialect")
+	ret0, _ := ret[0].(db.Dialect)
+	return ret0
+}
+
+// Dialect indicates an expected call of Dialect.
+func (mr *MockTXMockRecorder) Dialect() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dialect", reflect.TypeOf((*MockTX)(nil).Dialect))
+}
+
 // ExecContext mocks base method.
 func (m *MockTX) ExecContext(ctx kontext.Context, queryKey, query string, args ...interface{}) (db.Result, exception.Exception) {
 	m.ctrl.T.Helper()
@@ -218,6 +340,45 @@ func (mr *MockTXMockRecorder) ExecContext(ctx, queryKey, query interface{}, args
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*MockTX)(nil).ExecContext), varargs...)
 }
 
+// ExecNamed mocks base method.
+func (m *MockTX) ExecNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (db.Result, exception.Exception) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecNamed", ctx, queryKey, query, arg)
+	ret0, _ := ret[0].(db.Result)
+	ret1, _ := ret[1].(exception.Exception)
+	return ret0, ret1
+}
+
+// ExecNamed indicates an expected call of ExecNamed.
+func (mr *MockTXMockRecorder) ExecNamed(ctx, queryKey, query, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecNamed", reflect.TypeOf((*MockTX)(nil).ExecNamed), ctx, queryKey, query, arg)
+}
+
+// OnCommit mocks base method.
+func (m *MockTX) OnCommit(ctx kontext.Context, f func(kontext.Context)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnCommit", ctx, f)
+}
+
+// OnCommit indicates an expected call of OnCommit.
+func (mr *MockTXMockRecorder) OnCommit(ctx, f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnCommit", reflect.TypeOf((*MockTX)(nil).OnCommit), ctx, f)
+}
+
+// OnRollback mocks base method.
+func (m *MockTX) OnRollback(f func(kontext.Context)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnRollback", f)
+}
+
+// OnRollback indicates an expected call of OnRollback.
+func (mr *MockTXMockRecorder) OnRollback(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRollback", reflect.TypeOf((*MockTX)(nil).OnRollback), f)
+}
+
 // QueryContext mocks base method.
 func (m *MockTX) QueryContext(ctx kontext.Context, queryKey, query string, args ...interface{}) (db.Rows, exception.Exception) {
 	m.ctrl.T.Helper()
@@ -238,6 +399,21 @@ func (mr *MockTXMockRecorder) QueryContext(ctx, queryKey, query interface{}, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*MockTX)(nil).QueryContext), varargs...)
 }
 
+// QueryNamed mocks base method.
+func (m *MockTX) QueryNamed(ctx kontext.Context, queryKey, query string, arg interface{}) (db.Rows, exception.Exception) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryNamed", ctx, queryKey, query, arg)
+	ret0, _ := ret[0].(db.Rows)
+	ret1, _ := ret[1].(exception.Exception)
+	return ret0, ret1
+}
+
+// QueryNamed indicates an expected call of QueryNamed.
+func (mr *MockTXMockRecorder) QueryNamed(ctx, queryKey, query, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryNamed", reflect.TypeOf((*MockTX)(nil).QueryNamed), ctx, queryKey, query, arg)
+}
+
 // QueryRowContext mocks base method.
 func (m *MockTX) QueryRowContext(ctx kontext.Context, queryKey, query string, args ...interface{}) db.Row {
 	m.ctrl.T.Helper()
@@ -257,6 +433,25 @@ func (mr *MockTXMockRecorder) QueryRowContext(ctx, queryKey, query interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*MockTX)(nil).QueryRowContext), varargs...)
 }
 
+// Transaction mocks base method.
+func (m *MockTX) Transaction(ctx kontext.Context, transactionKey string, f func(db.TX) exception.Exception, opts ...db.TransactionOption) exception.Exception {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, transactionKey, f}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transaction", varargs...)
+	ret0, _ := ret[0].(exception.Exception)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockTXMockRecorder) Transaction(ctx, transactionKey, f interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, transactionKey, f}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockTX)(nil).Transaction), varargs...)
+}
+
 // MockResult is a mock of Result interface.
 type MockResult struct {
 	ctrl     *gomock.Controller
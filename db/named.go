@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// BindNamed rewrite `:name` placeholders of query into dialect placeholders and return positional args.
+//
+// arg is either map with string key or struct (or pointer to struct) mapped by `db` tag the same way as ScanAll.
+// Slice value is expanded into comma separated placeholders so it can be used in `IN (:ids)`, except []byte and driver.Valuer.
+// Placeholder inside quoted text and postgres `::type` cast is left as it is.
+func BindNamed(dialect Dialect, query string, arg interface{}) (string, []interface{}, exception.Exception) {
+	lookup, exc := namedLookup(arg)
+	if exc != nil {
+		return "", nil, exc
+	}
+
+	var builder strings.Builder
+	var args []interface{}
+	var quote rune
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			builder.WriteString("::")
+			i++
+			continue
+		case char == ':' && i+1 < len(runes) && isNameRune(runes[i+1]):
+			end := i + 1
+			for end < len(runes) && isNameRune(runes[end]) {
+				end++
+			}
+
+			name := string(runes[i+1 : end])
+			value, ok := lookup(name)
+			if !ok {
+				return "", nil, exception.Throw(fmt.Errorf("named parameter :%s is not found in arg", name), exception.WithType(exception.BadInput))
+			}
+
+			values, exc := expandNamed(name, value)
+			if exc != nil {
+				return "", nil, exc
+			}
+
+			for j, v := range values {
+				if j > 0 {
+					builder.WriteString(", ")
+				}
+
+				args = append(args, v)
+				builder.WriteString(dialect.Placeholder(len(args)))
+			}
+
+			i = end - 1
+			continue
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String(), args, nil
+}
+
+func isNameRune(char rune) bool {
+	return char == '_' || char == '.' || unicode.IsLetter(char) || unicode.IsDigit(char)
+}
+
+func namedLookup(arg interface{}) (func(name string) (interface{}, bool), exception.Exception) {
+	value := reflect.ValueOf(arg)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch {
+	case value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String:
+		return func(name string) (interface{}, bool) {
+			v := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+
+			return v.Interface(), true
+		}, nil
+	case value.Kind() == reflect.Struct && !isScalar(value.Type()):
+		mapping := mappingOf(value.Type())
+		return func(name string) (interface{}, bool) {
+			field, ok := mapping.byColumn[name]
+			if !ok {
+				return nil, false
+			}
+
+			v, ok := valueByIndex(value, field.index)
+			if !ok {
+				return nil, true
+			}
+
+			return v.Interface(), true
+		}, nil
+	default:
+		return nil, exception.Throw(fmt.Errorf("named arg must be map with string key or struct, got %T", arg), exception.WithType(exception.BadInput))
+	}
+}
+
+// expandNamed return slice elements as separate args, other value is returned as single arg
+func expandNamed(name string, value interface{}) ([]interface{}, exception.Exception) {
+	if _, ok := value.(driver.Valuer); ok {
+		return []interface{}{value}, nil
+	}
+
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}, nil
+	}
+
+	if v.Len() == 0 {
+		return nil, exception.Throw(fmt.Errorf("named parameter :%s is an empty slice", name), exception.WithType(exception.BadInput))
+	}
+
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+
+	return values, nil
+}
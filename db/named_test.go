@@ -0,0 +1,133 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedFilter struct {
+	Status string  `db:"status"`
+	IDs    []int64 `db:"ids"`
+	Secret string  `db:"-"`
+	*Timestamp
+}
+
+func TestBindNamed(t *testing.T) {
+	t.Run("When arg is a map it will be bound into positional args", func(t *testing.T) {
+		query, args, exc := db.BindNamed(db.MySQL, "select * from users where status = :status and name = :name or status = :status", map[string]interface{}{"status": "active", "name": "john"})
+		assert.Nil(t, exc)
+		assert.Equal(t, "select * from users where status = ? and name = ? or status = ?", query)
+		assert.Equal(t, []interface{}{"active", "john", "active"}, args)
+	})
+
+	t.Run("When arg is a struct it will be bound by db tag and slice will be expanded", func(t *testing.T) {
+		query, args, exc := db.BindNamed(db.Postgres, "select * from users where status = :status and id in (:ids) and created_at < :created_at", &namedFilter{Status: "active", IDs: []int64{1, 2, 3}})
+		assert.Nil(t, exc)
+		assert.Equal(t, "select * from users where status = $1 and id in ($2, $3, $4) and created_at < $5", query)
+		assert.Equal(t, []interface{}{"active", int64(1), int64(2), int64(3), nil}, args)
+	})
+
+	t.Run("When placeholder is quoted or postgres cast it will be left as it is", func(t *testing.T) {
+		query, args, exc := db.BindNamed(db.Postgres, "select ':status', created_at::date, data from users where status = :status and data = :data", map[string]interface{}{"status": "active", "data": []byte("raw")})
+		assert.Nil(t, exc)
+		assert.Equal(t, "select ':status', created_at::date, data from users where status = $1 and data = $2", query)
+		assert.Equal(t, []interface{}{"active", []byte("raw")}, args)
+	})
+
+	t.Run("When named parameter is missing it will return bad input exception", func(t *testing.T) {
+		_, _, exc := db.BindNamed(db.MySQL, "select * from users where status = :status", map[string]interface{}{})
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+
+	t.Run("When slice is empty it will return bad input exception", func(t *testing.T) {
+		_, _, exc := db.BindNamed(db.MySQL, "select * from users where id in (:ids)", namedFilter{})
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+
+	t.Run("When arg is not a map or struct it will return bad input exception", func(t *testing.T) {
+		_, _, exc := db.BindNamed(db.MySQL, "select * from users where id = :id", 1)
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+}
+
+func TestNamed(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When executing named query in transaction it will be executed with the same query key", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`update users set status = \? where id in \(\?, \?\)`).WithArgs("inactive", 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+		mockDB.ExpectQuery(`select id from users where status = \?`).WithArgs("inactive").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mockDB.ExpectCommit()
+
+		analyzer := &recordAnalyzer{}
+		exc := db.Adapt(sqldb, db.WithAnalyzer(analyzer)).Transaction(ktx, "deactivate-users", func(tx db.TX) exception.Exception {
+			result, exc := tx.ExecNamed(ktx, "deactivate-users", "update users set status = :status where id in (:ids)", map[string]interface{}{"status": "inactive", "ids": []int{1, 2}})
+			if exc != nil {
+				return exc
+			}
+
+			affected, _ := result.RowsAffected()
+			assert.Equal(t, int64(2), affected)
+
+			rows, exc := tx.QueryNamed(ktx, "list-users", "select id from users where status = :status", namedFilter{Status: "inactive"})
+			if exc != nil {
+				return exc
+			}
+
+			ids, exc := db.ScanAll[int](rows)
+			assert.Equal(t, []int{1, 2}, ids)
+			return exc
+		})
+		assert.Nil(t, exc)
+
+		assert.Equal(t, 3, len(analyzer.after))
+		assert.Equal(t, "deactivate-users", analyzer.after[0].Key)
+		assert.Equal(t, "update users set status = ? where id in (?, ?)", analyzer.after[0].SQL)
+		assert.Equal(t, 3, analyzer.after[0].ArgsCount)
+		assert.Equal(t, "list-users", analyzer.after[1].Key)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When executing named query outside of transaction it will be executed with the same query key", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectExec(`delete from users where id = \?`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectQuery(`select id from users where id = \?`).WithArgs(1).WillReturnError(sql.ErrConnDone)
+
+		adapter := db.Adapt(sqldb)
+		_, exc := adapter.ExecNamed(ktx, "delete-user", "delete from users where id = :id", map[string]int{"id": 1})
+		assert.Nil(t, exc)
+
+		rows, exc := adapter.QueryNamed(ktx, "find-user", "select id from users where id = :id", map[string]int{"id": 1})
+		assert.NotNil(t, exc)
+		assert.False(t, rows.Next())
+		assert.Nil(t, rows.Close())
+
+		_, exc = adapter.ExecNamed(ktx, "delete-user", "delete from users where id = :id", map[string]int{})
+		assert.Equal(t, exception.BadInput, exc.Type())
+
+		rows, exc = adapter.QueryNamed(ktx, "find-user", "select id from users where id = :id", map[string]int{})
+		assert.Equal(t, exception.BadInput, exc.Type())
+		assert.False(t, rows.Next())
+		assert.Nil(t, rows.Err())
+		assert.NotNil(t, rows.Scan())
+		assert.Nil(t, rows.Close())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
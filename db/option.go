@@ -1 +1,37 @@
 package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TransactionConfig carry transaction config
+type TransactionConfig struct {
+	isolation sql.IsolationLevel
+	readOnly  bool
+	timeout   time.Duration
+}
+
+// TransactionOption when starting transaction, it is ignored in nested transaction
+type TransactionOption func(*TransactionConfig)
+
+// WithIsolationLevel start transaction with given isolation level, default to the database default isolation level
+func WithIsolationLevel(isolation sql.IsolationLevel) TransactionOption {
+	return func(c *TransactionConfig) {
+		c.isolation = isolation
+	}
+}
+
+// WithReadOnly start read only transaction
+func WithReadOnly() TransactionOption {
+	return func(c *TransactionConfig) {
+		c.readOnly = true
+	}
+}
+
+// WithTimeout start transaction with deadline derived from the kontext, the transaction is rolled back when the deadline exceeded
+func WithTimeout(timeout time.Duration) TransactionOption {
+	return func(c *TransactionConfig) {
+		c.timeout = timeout
+	}
+}
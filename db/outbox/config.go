@@ -0,0 +1,68 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// FailureReporter receive every event which is failed to be published, the event is retried on the next poll after the backoff
+type FailureReporter func(ktx kontext.Context, event Event, exc exception.Exception)
+
+// Config carry outbox config
+type Config struct {
+	table           string
+	pollInterval    time.Duration
+	batchSize       int
+	baseBackoff     time.Duration
+	maxBackoff      time.Duration
+	lockName        string
+	failureReporter FailureReporter
+}
+
+// Option of outbox
+type Option func(*Config)
+
+// WithTable set outbox table, default to "outbox"
+func WithTable(table string) Option {
+	return func(c *Config) {
+		c.table = table
+	}
+}
+
+// WithPollInterval set interval of relay polling pending events, default to 1 second
+func WithPollInterval(pollInterval time.Duration) Option {
+	return func(c *Config) {
+		c.pollInterval = pollInterval
+	}
+}
+
+// WithBatchSize set maximum pending events read on each poll, default to 100
+func WithBatchSize(batchSize int) Option {
+	return func(c *Config) {
+		c.batchSize = batchSize
+	}
+}
+
+// WithRetryBackoff set exponential backoff of failed event and its cap, default to 1 second and 1 minute
+func WithRetryBackoff(baseBackoff, maxBackoff time.Duration) Option {
+	return func(c *Config) {
+		c.baseBackoff = baseBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithRelayLock make relay poll only while holding advisory lock of given name using db.DB Lock, so several instances can run the relay without publishing the same event twice
+func WithRelayLock(name string) Option {
+	return func(c *Config) {
+		c.lockName = name
+	}
+}
+
+// WithFailureReporter set reporter of failed event
+func WithFailureReporter(failureReporter FailureReporter) Option {
+	return func(c *Config) {
+		c.failureReporter = failureReporter
+	}
+}
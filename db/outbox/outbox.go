@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Event stored in the outbox table, events of the same aggregate key is published in the order it is appended
+type Event struct {
+	ID           int64     `db:"id"`
+	AggregateKey string    `db:"aggregate_key"`
+	Topic        string    `db:"topic"`
+	Payload      []byte    `db:"payload"`
+	Attempts     int       `db:"attempts"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// Publisher publish event into message broker, returning exception make the event retried later.
+// Event is delivered at least once so the consumer must be idempotent, use ID as deduplication key.
+type Publisher interface {
+	Publish(ktx kontext.Context, event Event) exception.Exception
+}
+
+// PublisherFunc adapt function into Publisher
+type PublisherFunc func(ktx kontext.Context, event Event) exception.Exception
+
+// Publish call f
+func (f PublisherFunc) Publish(ktx kontext.Context, event Event) exception.Exception {
+	return f(ktx, event)
+}
+
+// Outbox append events in the same transaction as the business data and relay it into Publisher in background,
+// so both is written atomically without two-phase commit.
+type Outbox struct {
+	db        db.DB
+	publisher Publisher
+	config    Config
+
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Fabricate outbox of database, publisher can be nil when the service only append events and the relay is run somewhere else
+func Fabricate(database db.DB, publisher Publisher, opts ...Option) *Outbox {
+	var config Config
+
+	// Default value
+	config.table = "outbox"
+	config.pollInterval = time.Second
+	config.batchSize = 100
+	config.baseBackoff = time.Second
+	config.maxBackoff = time.Minute
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Outbox{db: database, publisher: publisher, config: config}
+}
+
+// Append events into outbox table using tx, pass transaction of db.DB Transaction so the events is only published when the transaction is committed.
+// Only AggregateKey, Topic and Payload of the event is used, event without aggregate key or topic is returned as exception.BadInput.
+func (o *Outbox) Append(ktx kontext.Context, tx db.TX, events ...Event) exception.Exception {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	rows := make([][]interface{}, 0, len(events))
+	for _, event := range events {
+		if event.AggregateKey == "" || event.Topic == "" {
+			return exception.Throw(fmt.Errorf("outbox event require aggregate key and topic"), exception.WithType(exception.BadInput))
+		}
+
+		payload := event.Payload
+		if payload == nil {
+			payload = []byte{}
+		}
+
+		rows = append(rows, []interface{}{event.AggregateKey, event.Topic, payload, 0, now, now})
+	}
+
+	_, exc := db.BulkInsert(ktx, tx, "outbox.append", o.config.table, []string{"aggregate_key", "topic", "payload", "attempts", "available_at", "created_at"}, rows)
+	return exc
+}
+
+// Schema return statements creating outbox table and its index using Dialect CreateTable of database, put it into migration file or execute it directly
+func (o *Outbox) Schema() []string {
+	table := o.config.table
+
+	return o.db.Dialect().CreateTable(db.Table{
+		Name: table,
+		Columns: []db.Column{
+			{Name: "id", Type: db.AutoIncrement},
+			{Name: "aggregate_key", Type: db.String},
+			{Name: "topic", Type: db.String},
+			{Name: "payload", Type: db.Bytes},
+			{Name: "attempts", Type: db.Integer, Default: "0"},
+			{Name: "last_error", Type: db.Text, Null: true},
+			{Name: "available_at", Type: db.Timestamp},
+			{Name: "created_at", Type: db.Timestamp},
+			{Name: "sent_at", Type: db.Timestamp, Null: true},
+		},
+		Indexes: []db.Index{
+			{Name: table + "_pending", Columns: []string{"sent_at", "id"}},
+			{Name: table + "_aggregate", Columns: []string{"aggregate_key", "id"}},
+		},
+	})
+}
+
+// query format outbox table into the query and rebind it into the dialect
+func (o *Outbox) query(format string) string {
+	return db.Rebind(o.db.Dialect(), fmt.Sprintf(format, o.config.table))
+}
@@ -0,0 +1,209 @@
+package outbox_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/fake"
+	"github.com/kodefluence/monorepo/db/outbox"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type publisher struct {
+	mutex     sync.Mutex
+	published []outbox.Event
+	failures  map[string]int
+}
+
+func (p *publisher) Publish(ktx kontext.Context, event outbox.Event) exception.Exception {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.failures[string(event.Payload)] > 0 {
+		p.failures[string(event.Payload)]--
+		return exception.Throw(errors.New("broker unavailable"), exception.WithType(exception.Unavailable))
+	}
+
+	p.published = append(p.published, event)
+	return nil
+}
+
+func (p *publisher) payloads() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	payloads := []string{}
+	for _, event := range p.published {
+		payloads = append(payloads, string(event.Payload))
+	}
+
+	return payloads
+}
+
+func fabricateDB(t *testing.T, name string) db.DB {
+	sqldb, exc := sqlite.Fabricate(name, db.Config{Name: sqlite.Memory})
+	assert.Nil(t, exc)
+	t.Cleanup(func() { sqldb.Eject().Close() })
+
+	for _, statement := range outbox.Fabricate(sqldb, nil).Schema() {
+		_, exc := sqldb.ExecContext(kontext.Fabricate(), "outbox.schema", statement)
+		assert.Nil(t, exc)
+	}
+
+	return sqldb
+}
+
+func TestOutbox(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When events is appended inside transaction it will only be stored when the transaction is committed", func(t *testing.T) {
+		sqldb := fabricateDB(t, "outbox_append_db")
+		ob := outbox.Fabricate(sqldb, nil)
+
+		exc := sqldb.Transaction(ktx, "order.create", func(tx db.TX) exception.Exception {
+			return ob.Append(ktx, tx, outbox.Event{AggregateKey: "order-1", Topic: "order.created", Payload: []byte(`{"id":1}`)}, outbox.Event{AggregateKey: "order-1", Topic: "order.paid"})
+		})
+		assert.Nil(t, exc)
+
+		exc = sqldb.Transaction(ktx, "order.create", func(tx db.TX) exception.Exception {
+			if exc := ob.Append(ktx, tx, outbox.Event{AggregateKey: "order-2", Topic: "order.created"}); exc != nil {
+				return exc
+			}
+
+			return exception.Throw(errors.New("payment declined"))
+		})
+		assert.NotNil(t, exc)
+
+		rows, exc := sqldb.QueryContext(ktx, "outbox.list", "SELECT id, aggregate_key, topic, payload, attempts, created_at FROM outbox ORDER BY id")
+		assert.Nil(t, exc)
+
+		events, exc := db.ScanAll[outbox.Event](rows)
+		assert.Nil(t, exc)
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, "order.created", events[0].Topic)
+			assert.Equal(t, []byte(`{"id":1}`), events[0].Payload)
+			assert.Equal(t, "order.paid", events[1].Topic)
+		}
+
+		exc = sqldb.Transaction(ktx, "order.create", func(tx db.TX) exception.Exception {
+			return ob.Append(ktx, tx, outbox.Event{AggregateKey: "order-3"})
+		})
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+
+	t.Run("When publishing failed it will retry the event and hold the rest of its aggregate", func(t *testing.T) {
+		sqldb := fabricateDB(t, "outbox_relay_db")
+		pub := &publisher{failures: map[string]int{"a1": 1}}
+
+		var reported []outbox.Event
+		ob := outbox.Fabricate(sqldb, pub, outbox.WithRetryBackoff(0, 0), outbox.WithFailureReporter(func(ktx kontext.Context, event outbox.Event, exc exception.Exception) {
+			reported = append(reported, event)
+		}))
+
+		exc := sqldb.Transaction(ktx, "outbox.append", func(tx db.TX) exception.Exception {
+			return ob.Append(ktx, tx,
+				outbox.Event{AggregateKey: "a", Topic: "created", Payload: []byte("a1")},
+				outbox.Event{AggregateKey: "b", Topic: "created", Payload: []byte("b1")},
+				outbox.Event{AggregateKey: "a", Topic: "updated", Payload: []byte("a2")},
+			)
+		})
+		assert.Nil(t, exc)
+
+		published, exc := ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []string{"b1"}, pub.payloads())
+		if assert.Len(t, reported, 1) {
+			assert.Equal(t, "a1", string(reported[0].Payload))
+		}
+
+		published, exc = ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.Equal(t, 2, published)
+		assert.Equal(t, []string{"b1", "a1", "a2"}, pub.payloads())
+		assert.Equal(t, 1, pub.published[1].Attempts)
+
+		published, exc = ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.Equal(t, 0, published)
+
+		var lastError string
+		assert.Nil(t, sqldb.QueryRowContext(ktx, "outbox.last_error", "SELECT last_error FROM outbox WHERE payload = ?", []byte("a1")).Scan(&lastError))
+		assert.Equal(t, "broker unavailable", lastError)
+	})
+
+	t.Run("When events of an aggregate is waiting for its backoff it will not block other aggregates", func(t *testing.T) {
+		sqldb := fabricateDB(t, "outbox_blocked_db")
+		pub := &publisher{failures: map[string]int{"a1": 10}}
+		ob := outbox.Fabricate(sqldb, pub, outbox.WithBatchSize(3), outbox.WithRetryBackoff(time.Minute, time.Minute))
+
+		exc := sqldb.Transaction(ktx, "outbox.append", func(tx db.TX) exception.Exception {
+			return ob.Append(ktx, tx,
+				outbox.Event{AggregateKey: "a", Topic: "created", Payload: []byte("a1")},
+				outbox.Event{AggregateKey: "a", Topic: "updated", Payload: []byte("a2")},
+				outbox.Event{AggregateKey: "a", Topic: "deleted", Payload: []byte("a3")},
+				outbox.Event{AggregateKey: "b", Topic: "created", Payload: []byte("b1")},
+			)
+		})
+		assert.Nil(t, exc)
+
+		published, exc := ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.Equal(t, 0, published)
+
+		published, exc = ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []string{"b1"}, pub.payloads())
+	})
+
+	t.Run("When relay is started it will publish in background until stopped", func(t *testing.T) {
+		sqldb := fabricateDB(t, "outbox_start_db")
+		pub := &publisher{}
+		ob := outbox.Fabricate(sqldb, pub, outbox.WithPollInterval(5*time.Millisecond))
+
+		ob.Start(ktx)
+		ob.Start(ktx)
+		defer ob.Stop()
+
+		exc := sqldb.Transaction(ktx, "outbox.append", func(tx db.TX) exception.Exception {
+			return ob.Append(ktx, tx, outbox.Event{AggregateKey: "a", Topic: "created", Payload: []byte("a1")})
+		})
+		assert.Nil(t, exc)
+
+		assert.Eventually(t, func() bool { return len(pub.payloads()) == 1 }, time.Second, 5*time.Millisecond)
+		ob.Stop()
+		ob.Stop()
+	})
+
+	t.Run("When relay lock is held by another instance it will skip the poll", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		ob := outbox.Fabricate(fakeDB, &publisher{}, outbox.WithRelayLock("outbox-relay"))
+
+		lock, exc := fakeDB.Lock(ktx, "outbox-relay", 0)
+		assert.Nil(t, exc)
+
+		published, exc := ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.Equal(t, 0, published)
+		assert.False(t, fakeDB.Executed("outbox.pending"))
+
+		assert.Nil(t, lock.Release(ktx))
+
+		_, exc = ob.Relay(ktx)
+		assert.Nil(t, exc)
+		assert.True(t, fakeDB.Executed("outbox.pending"))
+		assert.False(t, fakeDB.Locked("outbox-relay"))
+	})
+
+	t.Run("When relay has no publisher it will return bad input exception", func(t *testing.T) {
+		_, exc := outbox.Fabricate(fake.Fabricate(), nil).Relay(ktx)
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+}
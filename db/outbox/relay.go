@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/internal/backoff"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Relay poll pending events once and publish it, the number of published events is returned.
+// Events of the same aggregate key is published in order, when one of it failed or still waiting for its backoff the rest is held until the next poll.
+// Held events is not read so events of other aggregates is still published.
+// Event which is published but failed to be marked as sent is published again on the next poll.
+func (o *Outbox) Relay(ktx kontext.Context) (int, exception.Exception) {
+	if o.publisher == nil {
+		return 0, exception.Throw(fmt.Errorf("outbox relay require publisher"), exception.WithType(exception.BadInput))
+	}
+
+	if o.config.lockName != "" {
+		lock, exc := o.db.Lock(ktx, o.config.lockName, 0)
+		if exc != nil {
+			// Another instance is relaying
+			if exc.Type() == exception.Conflict {
+				return 0, nil
+			}
+
+			return 0, exc
+		}
+		defer func() { _ = lock.Release(ktx) }()
+	}
+
+	now := time.Now().UTC()
+
+	// Event is pending when it is due and there is no older event of the same aggregate waiting for its backoff
+	rows, exc := o.db.QueryContext(ktx, "outbox.pending", o.query(`SELECT id, aggregate_key, topic, payload, attempts, created_at FROM %[1]s AS pending
+		WHERE sent_at IS NULL AND available_at <= ?
+		AND NOT EXISTS (SELECT 1 FROM %[1]s AS held WHERE held.aggregate_key = pending.aggregate_key AND held.sent_at IS NULL AND held.id < pending.id AND held.available_at > ?)
+		ORDER BY id LIMIT ?`), now, now, o.config.batchSize)
+	if exc != nil {
+		return 0, exc
+	}
+
+	pending, exc := db.ScanAll[Event](rows)
+	if exc != nil {
+		return 0, exc
+	}
+
+	held := map[string]bool{}
+	published := 0
+
+	for _, event := range pending {
+		if err := ktx.Ctx().Err(); err != nil {
+			return published, exception.Throw(err)
+		}
+
+		if held[event.AggregateKey] {
+			continue
+		}
+
+		if exc := o.publisher.Publish(ktx, event); exc != nil {
+			held[event.AggregateKey] = true
+
+			if o.config.failureReporter != nil {
+				o.config.failureReporter(ktx, event, exc)
+			}
+
+			attempts := event.Attempts + 1
+			if _, exc := o.db.ExecContext(ktx, "outbox.failed", o.query("UPDATE %s SET attempts = ?, last_error = ?, available_at = ? WHERE id = ?"), attempts, exc.Error(), now.Add(backoff.Exponential(o.config.baseBackoff, o.config.maxBackoff, attempts)), event.ID); exc != nil {
+				return published, exc
+			}
+
+			continue
+		}
+
+		if _, exc := o.db.ExecContext(ktx, "outbox.sent", o.query("UPDATE %s SET attempts = ?, sent_at = ? WHERE id = ?"), event.Attempts+1, now, event.ID); exc != nil {
+			return published, exc
+		}
+
+		published++
+	}
+
+	return published, nil
+}
+
+// Start relay in background, it poll every poll interval until Stop is called or the kontext is cancelled.
+// Failure of the poll itself is reported into failure reporter with empty event.
+func (o *Outbox) Start(ktx kontext.Context) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.stop != nil {
+		return
+	}
+
+	o.stop = make(chan struct{})
+	o.done = make(chan struct{})
+
+	go o.run(ktx, o.stop, o.done)
+}
+
+// Stop background relay and wait until the running poll is finished
+func (o *Outbox) Stop() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.stop == nil {
+		return
+	}
+
+	close(o.stop)
+	<-o.done
+
+	o.stop = nil
+	o.done = nil
+}
+
+func (o *Outbox) run(ktx kontext.Context, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(o.config.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, exc := o.Relay(ktx); exc != nil && o.config.failureReporter != nil && ktx.Ctx().Err() == nil {
+			o.config.failureReporter(ktx, Event{}, exc)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ktx.Ctx().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
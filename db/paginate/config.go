@@ -0,0 +1,91 @@
+package paginate
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Order of a column used as pagination key
+type Order struct {
+	Column     string
+	Descending bool
+}
+
+// Asc order column ascending
+func Asc(column string) Order {
+	return Order{Column: column}
+}
+
+// Desc order column descending
+func Desc(column string) Order {
+	return Order{Column: column, Descending: true}
+}
+
+// Config carry pagination config
+type Config struct {
+	orders  []Order
+	size    int
+	maxSize int
+	after   string
+	before  string
+	exc     exception.Exception
+}
+
+// Option of pagination
+type Option func(*Config)
+
+// WithOrder set ordering columns, the combination of its values must be unique and not null so include primary key as the last column
+func WithOrder(orders ...Order) Option {
+	return func(c *Config) {
+		c.orders = orders
+	}
+}
+
+// WithSize set page size, default to 20
+func WithSize(size int) Option {
+	return func(c *Config) {
+		c.size = size
+	}
+}
+
+// WithMaxSize cap page size requested by the client, default to 100
+func WithMaxSize(maxSize int) Option {
+	return func(c *Config) {
+		c.maxSize = maxSize
+	}
+}
+
+// WithAfter fetch page after the cursor, it is the NextCursor of previous page
+func WithAfter(cursor string) Option {
+	return func(c *Config) {
+		c.after = cursor
+	}
+}
+
+// WithBefore fetch page before the cursor, it is the PreviousCursor of previous page
+func WithBefore(cursor string) Option {
+	return func(c *Config) {
+		c.before = cursor
+	}
+}
+
+// WithURLQuery read page[size], page[after] and page[before] of request query, the same parameters written by Page.Links
+func WithURLQuery(query url.Values) Option {
+	return func(c *Config) {
+		if size := query.Get(sizeParam); size != "" {
+			parsed, err := strconv.Atoi(size)
+			if err != nil {
+				c.exc = exception.Throw(fmt.Errorf("invalid %s: %s", sizeParam, size), exception.WithType(exception.BadInput), exception.WithTitle("invalid page size"))
+				return
+			}
+
+			c.size = parsed
+		}
+
+		c.after = query.Get(afterParam)
+		c.before = query.Get(beforeParam)
+	}
+}
@@ -0,0 +1,129 @@
+package paginate
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// cursorValue keep go type of order column value so it is bound as the same type when the cursor is decoded
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+// encodeCursor encode order column values into opaque url safe string
+func encodeCursor(values []interface{}) (string, exception.Exception) {
+	encoded := make([]cursorValue, len(values))
+	for i, value := range values {
+		v, exc := encodeCursorValue(value)
+		if exc != nil {
+			return "", exc
+		}
+
+		encoded[i] = v
+	}
+
+	content, err := json.Marshal(encoded)
+	if err != nil {
+		return "", exception.Throw(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(content), nil
+}
+
+func encodeCursorValue(value interface{}) (cursorValue, exception.Exception) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return cursorValue{}, exception.Throw(err)
+		}
+		value = v
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Invalid:
+		return cursorValue{}, exception.Throw(fmt.Errorf("order column value is null"), exception.WithType(exception.BadInput))
+	case reflect.Ptr:
+		if reflected.IsNil() {
+			return cursorValue{}, exception.Throw(fmt.Errorf("order column value is null"), exception.WithType(exception.BadInput))
+		}
+
+		return encodeCursorValue(reflected.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Type: "int", Value: strconv.FormatInt(reflected.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Type: "uint", Value: strconv.FormatUint(reflected.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: "float", Value: strconv.FormatFloat(reflected.Float(), 'g', -1, 64)}, nil
+	case reflect.Bool:
+		return cursorValue{Type: "bool", Value: strconv.FormatBool(reflected.Bool())}, nil
+	case reflect.String:
+		return cursorValue{Type: "string", Value: reflected.String()}, nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return cursorValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return cursorValue{Type: "time", Value: v.Format(time.RFC3339Nano)}, nil
+	}
+
+	return cursorValue{}, exception.Throw(fmt.Errorf("unsupported order column value %T", value), exception.WithType(exception.BadInput))
+}
+
+// decodeCursor decode cursor written by encodeCursor, cursor which is tampered or has different number of values is returned as exception.BadInput
+func decodeCursor(cursor string, size int) ([]interface{}, exception.Exception) {
+	invalid := func(err error) exception.Exception {
+		return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid cursor"))
+	}
+
+	content, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	var encoded []cursorValue
+	if err := json.Unmarshal(content, &encoded); err != nil {
+		return nil, invalid(err)
+	}
+
+	if len(encoded) != size {
+		return nil, invalid(fmt.Errorf("cursor has %d values, expected %d", len(encoded), size))
+	}
+
+	values := make([]interface{}, len(encoded))
+	for i, v := range encoded {
+		switch v.Type {
+		case "int":
+			values[i], err = strconv.ParseInt(v.Value, 10, 64)
+		case "uint":
+			values[i], err = strconv.ParseUint(v.Value, 10, 64)
+		case "float":
+			values[i], err = strconv.ParseFloat(v.Value, 64)
+		case "bool":
+			values[i], err = strconv.ParseBool(v.Value)
+		case "string":
+			values[i] = v.Value
+		case "bytes":
+			values[i], err = base64.StdEncoding.DecodeString(v.Value)
+		case "time":
+			values[i], err = time.Parse(time.RFC3339Nano, v.Value)
+		default:
+			err = fmt.Errorf("unsupported cursor value type %s", v.Type)
+		}
+
+		if err != nil {
+			return nil, invalid(err)
+		}
+	}
+
+	return values, nil
+}
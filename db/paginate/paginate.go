@@ -0,0 +1,277 @@
+package paginate
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/jsonapi"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+const (
+	sizeParam   = "page[size]"
+	afterParam  = "page[after]"
+	beforeParam = "page[before]"
+)
+
+// Page of items fetched by Query
+type Page[T any] struct {
+	Items          []T
+	Size           int
+	HasNext        bool
+	HasPrevious    bool
+	NextCursor     string
+	PreviousCursor string
+}
+
+// Query fetch a page of T using keyset pagination instead of LIMIT/OFFSET so it stay fast on large tables.
+// The query is wrapped as `SELECT * FROM (query) AS paginated WHERE <keyset> ORDER BY <orders> LIMIT <size + 1>`,
+// so it must not have its own ORDER BY or LIMIT and every order column must be selected and mapped into T, see db.Columns.
+// Placeholders of the keyset is positioned after args so postgres query can use $1 until $n of its own args.
+// Page which is requested with invalid cursor or size is returned as exception.BadInput.
+func Query[T any](ktx kontext.Context, tx db.TX, queryKey, query string, args []interface{}, opts ...Option) (Page[T], exception.Exception) {
+	var config Config
+
+	// Default value
+	config.size = 20
+	config.maxSize = 100
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	page := Page[T]{Items: []T{}}
+
+	if config.exc != nil {
+		return page, config.exc
+	}
+
+	if len(config.orders) == 0 {
+		return page, exception.Throw(fmt.Errorf("pagination of %s require order columns", queryKey), exception.WithType(exception.BadInput))
+	}
+
+	if config.size < 1 {
+		return page, exception.Throw(fmt.Errorf("invalid %s: %d", sizeParam, config.size), exception.WithType(exception.BadInput), exception.WithTitle("invalid page size"))
+	}
+
+	if config.size > config.maxSize {
+		config.size = config.maxSize
+	}
+	page.Size = config.size
+
+	if config.after != "" && config.before != "" {
+		return page, exception.Throw(fmt.Errorf("%s and %s can not be used together", afterParam, beforeParam), exception.WithType(exception.BadInput), exception.WithTitle("invalid cursor"))
+	}
+
+	indexes, exc := orderIndexes[T](config.orders)
+	if exc != nil {
+		return page, exc
+	}
+
+	backward := config.before != ""
+	cursor := config.after
+	if backward {
+		cursor = config.before
+	}
+
+	args = append([]interface{}{}, args...)
+
+	var builder strings.Builder
+	builder.WriteString("SELECT * FROM (")
+	builder.WriteString(query)
+	builder.WriteString(") AS paginated")
+
+	if cursor != "" {
+		values, exc := decodeCursor(cursor, len(config.orders))
+		if exc != nil {
+			return page, exc
+		}
+
+		builder.WriteString(" WHERE ")
+		args = keyset(&builder, tx.Dialect(), config.orders, values, backward, args)
+	}
+
+	builder.WriteString(" ORDER BY ")
+	for i, order := range config.orders {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString(order.Column)
+		if order.Descending != backward {
+			builder.WriteString(" DESC")
+		} else {
+			builder.WriteString(" ASC")
+		}
+	}
+
+	builder.WriteString(" LIMIT ")
+	builder.WriteString(strconv.Itoa(config.size + 1))
+
+	rows, exc := tx.QueryContext(ktx, queryKey, builder.String(), args...)
+	if exc != nil {
+		return page, exc
+	}
+
+	items, exc := db.ScanAll[T](rows)
+	if exc != nil {
+		return page, exc
+	}
+
+	more := len(items) > config.size
+	if more {
+		items = items[:config.size]
+	}
+
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+
+		page.HasPrevious, page.HasNext = more, true
+	} else {
+		page.HasPrevious, page.HasNext = cursor != "", more
+	}
+
+	page.Items = items
+	if len(items) == 0 {
+		// Nothing left in this direction, client can go back using the same cursor
+		if backward {
+			page.NextCursor = cursor
+		} else if cursor != "" {
+			page.PreviousCursor = cursor
+		}
+
+		return page, nil
+	}
+
+	if page.HasNext {
+		if page.NextCursor, exc = itemCursor(items[len(items)-1], indexes); exc != nil {
+			return page, exc
+		}
+	}
+
+	if page.HasPrevious {
+		if page.PreviousCursor, exc = itemCursor(items[0], indexes); exc != nil {
+			return page, exc
+		}
+	}
+
+	return page, nil
+}
+
+// Meta of the page which can be put into jsonapi.Meta, for example jsonapi.WithMeta("page", page.Meta())
+func (p Page[T]) Meta() jsonapi.Meta {
+	meta := jsonapi.Meta{
+		"size":         p.Size,
+		"has_next":     p.HasNext,
+		"has_previous": p.HasPrevious,
+	}
+
+	if p.NextCursor != "" {
+		meta["next_cursor"] = p.NextCursor
+	}
+
+	if p.PreviousCursor != "" {
+		meta["previous_cursor"] = p.PreviousCursor
+	}
+
+	return meta
+}
+
+// Links of the page built from request url, next and prev link is set with page[after] or page[before] cursor while other query parameters is kept as it is
+func (p Page[T]) Links(self *url.URL) jsonapi.Links {
+	links := jsonapi.Links{"self": self.String()}
+
+	link := func(param, cursor string) string {
+		u := *self
+		query := u.Query()
+		query.Del(afterParam)
+		query.Del(beforeParam)
+		query.Set(param, cursor)
+		query.Set(sizeParam, strconv.Itoa(p.Size))
+		u.RawQuery = query.Encode()
+
+		return u.String()
+	}
+
+	if p.HasNext && p.NextCursor != "" {
+		links["next"] = link(afterParam, p.NextCursor)
+	}
+
+	if p.HasPrevious && p.PreviousCursor != "" {
+		links["prev"] = link(beforeParam, p.PreviousCursor)
+	}
+
+	return links
+}
+
+// keyset write condition of rows after the cursor values, for example order (a ASC, b DESC) is written as `(a > ?) OR (a = ? AND b < ?)`
+func keyset(builder *strings.Builder, dialect db.Dialect, orders []Order, values []interface{}, backward bool, args []interface{}) []interface{} {
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return dialect.Placeholder(len(args))
+	}
+
+	for i, order := range orders {
+		if i > 0 {
+			builder.WriteString(" OR ")
+		}
+
+		builder.WriteString("(")
+		for j := 0; j < i; j++ {
+			builder.WriteString(orders[j].Column)
+			builder.WriteString(" = ")
+			builder.WriteString(placeholder(values[j]))
+			builder.WriteString(" AND ")
+		}
+
+		builder.WriteString(order.Column)
+		if order.Descending != backward {
+			builder.WriteString(" < ")
+		} else {
+			builder.WriteString(" > ")
+		}
+		builder.WriteString(placeholder(values[i]))
+		builder.WriteString(")")
+	}
+
+	return args
+}
+
+// orderIndexes return index of order columns in db.Columns of T
+func orderIndexes[T any](orders []Order) ([]int, exception.Exception) {
+	columns := db.Columns[T]()
+
+	indexes := make([]int, len(orders))
+	for i, order := range orders {
+		indexes[i] = -1
+		for j, column := range columns {
+			if column == order.Column {
+				indexes[i] = j
+			}
+		}
+
+		if indexes[i] < 0 {
+			var t T
+			return nil, exception.Throw(fmt.Errorf("order column %s is not mapped in %T", order.Column, t), exception.WithType(exception.BadInput))
+		}
+	}
+
+	return indexes, nil
+}
+
+func itemCursor[T any](item T, indexes []int) (string, exception.Exception) {
+	fields := db.Values(item)
+
+	values := make([]interface{}, len(indexes))
+	for i, index := range indexes {
+		values[i] = fields[index]
+	}
+
+	return encodeCursor(values)
+}
@@ -0,0 +1,155 @@
+package paginate_test
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/paginate"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	TenantID  int64     `db:"tenant_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func ids(users []user) []int64 {
+	result := []int64{}
+	for _, u := range users {
+		result = append(result, u.ID)
+	}
+
+	return result
+}
+
+func TestQuery(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	sqldb, exc := sqlite.Fabricate("paginate_db", db.Config{Name: sqlite.Memory})
+	assert.Nil(t, exc)
+	defer sqldb.Eject().Close()
+
+	_, exc = sqldb.ExecContext(ktx, "users.create_table", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, tenant_id INTEGER NOT NULL, created_at DATETIME NOT NULL)")
+	assert.Nil(t, exc)
+
+	// Users 1 until 7 of tenant 1 where each pair of users is created at the same time, user 8 belong to another tenant
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for id := 1; id <= 8; id++ {
+		tenantID := 1
+		if id == 8 {
+			tenantID = 2
+		}
+
+		_, exc = sqldb.ExecContext(ktx, "users.insert", "INSERT INTO users (id, name, tenant_id, created_at) VALUES (?, ?, ?, ?)", id, fmt.Sprintf("user-%d", id), tenantID, createdAt.Add(time.Duration((id-1)/2)*time.Hour))
+		assert.Nil(t, exc)
+	}
+
+	query := "SELECT id, name, tenant_id, created_at FROM users WHERE tenant_id = ?"
+	args := []interface{}{1}
+	order := paginate.WithOrder(paginate.Desc("created_at"), paginate.Asc("id"))
+
+	t.Run("When paginating forward and backward it will follow the keyset order", func(t *testing.T) {
+		first, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithSize(3))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{7, 5, 6}, ids(first.Items))
+		assert.True(t, first.HasNext)
+		assert.False(t, first.HasPrevious)
+		assert.Empty(t, first.PreviousCursor)
+
+		second, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithSize(3), paginate.WithAfter(first.NextCursor))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{3, 4, 1}, ids(second.Items))
+		assert.True(t, second.HasNext)
+		assert.True(t, second.HasPrevious)
+
+		last, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithSize(3), paginate.WithAfter(second.NextCursor))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{2}, ids(last.Items))
+		assert.False(t, last.HasNext)
+		assert.True(t, last.HasPrevious)
+
+		back, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithSize(3), paginate.WithBefore(last.PreviousCursor))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{3, 4, 1}, ids(back.Items))
+		assert.True(t, back.HasNext)
+		assert.True(t, back.HasPrevious)
+
+		back, exc = paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithSize(3), paginate.WithBefore(back.PreviousCursor))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{7, 5, 6}, ids(back.Items))
+		assert.True(t, back.HasNext)
+		assert.False(t, back.HasPrevious)
+	})
+
+	t.Run("When paginating inside transaction it will use the transaction", func(t *testing.T) {
+		exc := sqldb.Transaction(ktx, "users.list", func(tx db.TX) exception.Exception {
+			page, exc := paginate.Query[user](ktx, tx, "users.list", "SELECT id, name, tenant_id, created_at FROM users", nil, paginate.WithOrder(paginate.Asc("id")), paginate.WithSize(500), paginate.WithMaxSize(5))
+			assert.Equal(t, 5, page.Size)
+			assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids(page.Items))
+			return exc
+		})
+		assert.Nil(t, exc)
+	})
+
+	t.Run("When reading page from request url it will build meta and links of the page", func(t *testing.T) {
+		self, _ := url.Parse("https://example.com/users?filter=active&page%5Bsize%5D=2")
+
+		page, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithURLQuery(self.Query()))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{7, 5}, ids(page.Items))
+
+		assert.Equal(t, 2, page.Meta()["size"])
+		assert.Equal(t, true, page.Meta()["has_next"])
+		assert.Equal(t, page.NextCursor, page.Meta()["next_cursor"])
+		assert.NotContains(t, page.Meta(), "previous_cursor")
+
+		links := page.Links(self)
+		assert.Equal(t, self.String(), links["self"])
+		assert.NotContains(t, links, "prev")
+
+		next, _ := url.Parse(links["next"])
+		assert.Equal(t, "active", next.Query().Get("filter"))
+		assert.Equal(t, "2", next.Query().Get("page[size]"))
+
+		page, exc = paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithURLQuery(next.Query()))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{6, 3}, ids(page.Items))
+
+		prev, _ := url.Parse(page.Links(next)["prev"])
+		assert.Empty(t, prev.Query().Get("page[after]"))
+
+		page, exc = paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithURLQuery(prev.Query()))
+		assert.Nil(t, exc)
+		assert.Equal(t, []int64{7, 5}, ids(page.Items))
+	})
+
+	t.Run("When pagination is invalid it will return bad input exception", func(t *testing.T) {
+		first, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, order, paginate.WithSize(2))
+		assert.Nil(t, exc)
+
+		invalids := map[string][]paginate.Option{
+			"missing order":        {paginate.WithSize(2)},
+			"unmapped order":       {paginate.WithOrder(paginate.Asc("email"))},
+			"zero size":            {order, paginate.WithSize(0)},
+			"invalid size":         {order, paginate.WithURLQuery(url.Values{"page[size]": {"ten"}})},
+			"tampered cursor":      {order, paginate.WithAfter("not-a-cursor")},
+			"different order":      {paginate.WithOrder(paginate.Asc("id")), paginate.WithAfter(first.NextCursor)},
+			"after and before set": {order, paginate.WithAfter(first.NextCursor), paginate.WithBefore(first.NextCursor)},
+		}
+
+		for name, opts := range invalids {
+			_, exc := paginate.Query[user](ktx, sqldb, "users.list", query, args, opts...)
+			if assert.NotNil(t, exc, name) {
+				assert.Equal(t, exception.BadInput, exc.Type(), name)
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+// Package postgres register github.com/lib/pq driver used by db.Postgres dialect.
+// It is separated from package db so the driver is only linked into binary which import this package.
+package postgres
+
+import (
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+
+	// Import postgres driver
+	_ "github.com/lib/pq"
+)
+
+// Fabricate will fabricate postgres connection and wrap it into SQL interfaces
+func Fabricate(instanceName string, config db.Config, opts ...db.Option) (db.DB, exception.Exception) {
+	return db.Fabricate(instanceName, db.Postgres, config, opts...)
+}
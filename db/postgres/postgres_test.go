@@ -0,0 +1,32 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFabricate(t *testing.T) {
+	t.Run("Complete open config to postgres", func(t *testing.T) {
+		config := db.Config{
+			Username: "postgres",
+			Password: "rootpw",
+			Host:     "localhost",
+			Name:     "test_database",
+		}
+
+		sqldb, err := postgres.Fabricate("postgres_db", config, db.WithConnMaxLifetime(time.Second), db.WithMaxIdleConn(100), db.WithMaxOpenConn(100))
+		assert.NotNil(t, sqldb)
+		assert.Nil(t, err)
+		assert.Equal(t, db.Postgres, sqldb.Dialect())
+
+		sqldbvalue, err := db.GetInstance("postgres_db")
+		assert.NotNil(t, sqldbvalue)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 0, len(db.CloseAll()))
+	})
+}
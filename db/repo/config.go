@@ -0,0 +1,24 @@
+package repo
+
+// Config carry repository config
+type Config struct {
+	primaryKey     string
+	queryKeyPrefix string
+}
+
+// Option of repository
+type Option func(*Config)
+
+// WithPrimaryKey set primary key column, default to "id"
+func WithPrimaryKey(primaryKey string) Option {
+	return func(c *Config) {
+		c.primaryKey = primaryKey
+	}
+}
+
+// WithQueryKeyPrefix set prefix of query keys, default to the table name so the keys is "<table>.find_by_id", "<table>.insert" and so on
+func WithQueryKeyPrefix(queryKeyPrefix string) Option {
+	return func(c *Config) {
+		c.queryKeyPrefix = queryKeyPrefix
+	}
+}
@@ -0,0 +1,203 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// Repository provide CRUD of table mapped into struct T by `db` tag, see db.ScanAll for how the fields is mapped.
+// Every method receive db.TX so it can be used inside Transaction or directly with db.DB.
+// Each query is executed with "<table>.<operation>" query key such as "users.find_by_id" for instrumentation.
+type Repository[T any] struct {
+	table      string
+	columns    []string
+	primaryKey int
+	config     Config
+}
+
+// Fabricate repository of table, T must be a struct with mapped primary key column otherwise exception.BadInput is returned
+func Fabricate[T any](table string, opts ...Option) (*Repository[T], exception.Exception) {
+	var config Config
+
+	// Default value
+	config.primaryKey = "id"
+	config.queryKeyPrefix = table
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	columns := db.Columns[T]()
+	if columns == nil {
+		return nil, exception.Throw(fmt.Errorf("repository of %s require struct type, got %T", table, *new(T)), exception.WithType(exception.BadInput))
+	}
+
+	primaryKey := -1
+	for i, column := range columns {
+		if column == config.primaryKey {
+			primaryKey = i
+		}
+	}
+
+	if primaryKey < 0 {
+		return nil, exception.Throw(fmt.Errorf("primary key %s of %s is not mapped in %T", config.primaryKey, table, *new(T)), exception.WithType(exception.BadInput))
+	}
+
+	return &Repository[T]{table: table, columns: columns, primaryKey: primaryKey, config: config}, nil
+}
+
+// FindByID return row with given primary key, exception.NotFound is returned when there is no row
+func (r *Repository[T]) FindByID(ktx kontext.Context, tx db.TX, id interface{}) (T, exception.Exception) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(r.columns, ", "), r.table, r.config.primaryKey)
+
+	rows, exc := tx.QueryContext(ktx, r.queryKey("find_by_id"), db.Rebind(tx.Dialect(), query), id)
+	if exc != nil {
+		return *new(T), exc
+	}
+
+	return db.ScanOne[T](rows)
+}
+
+// FindWhere return every row matching where condition ordered by primary key, where use "?" bind variable and empty where return every row.
+// Where condition is written into query as it is so it must not come from user input, pass user input as args instead.
+func (r *Repository[T]) FindWhere(ktx kontext.Context, tx db.TX, where string, args ...interface{}) ([]T, exception.Exception) {
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s", strings.Join(r.columns, ", "), r.table, whereClause(where), r.config.primaryKey)
+
+	rows, exc := tx.QueryContext(ktx, r.queryKey("find_where"), db.Rebind(tx.Dialect(), query), args...)
+	if exc != nil {
+		return []T{}, exc
+	}
+
+	return db.ScanAll[T](rows)
+}
+
+// Count rows matching where condition, see FindWhere for the condition
+func (r *Repository[T]) Count(ktx kontext.Context, tx db.TX, where string, args ...interface{}) (int64, exception.Exception) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", r.table, whereClause(where))
+
+	var count int64
+	if exc := tx.QueryRowContext(ktx, r.queryKey("count"), db.Rebind(tx.Dialect(), query), args...).Scan(&count); exc != nil {
+		return 0, exc
+	}
+
+	return count, nil
+}
+
+// Insert value into the table. When its primary key is zero the column is left to the database
+// and the generated key is set into value, using RETURNING clause when Dialect Returning support it such as Postgres and SQLite, otherwise LastInsertId.
+func (r *Repository[T]) Insert(ktx kontext.Context, tx db.TX, value *T) exception.Exception {
+	values := db.Values(*value)
+	generated := values[r.primaryKey] == nil || reflect.ValueOf(values[r.primaryKey]).IsZero()
+
+	var columns []string
+	var args []interface{}
+	for i, column := range r.columns {
+		if generated && i == r.primaryKey {
+			continue
+		}
+
+		columns = append(columns, column)
+		args = append(args, values[i])
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", r.table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	pointer := db.Pointers(value)[r.primaryKey]
+
+	if !generated {
+		_, exc := tx.ExecContext(ktx, r.queryKey("insert"), db.Rebind(tx.Dialect(), query), args...)
+		return exc
+	}
+
+	if tx.Dialect().Returning() {
+		query = fmt.Sprintf("%s RETURNING %s", query, r.config.primaryKey)
+		return tx.QueryRowContext(ktx, r.queryKey("insert"), db.Rebind(tx.Dialect(), query), args...).Scan(pointer)
+	}
+
+	result, exc := tx.ExecContext(ktx, r.queryKey("insert"), db.Rebind(tx.Dialect(), query), args...)
+	if exc != nil {
+		return exc
+	}
+
+	id, exc := result.LastInsertId()
+	if exc != nil {
+		return exc
+	}
+
+	return setInt(pointer, id)
+}
+
+// Update every column of value except its primary key. Affected rows is not checked since MySQL report zero affected row when nothing is changed.
+func (r *Repository[T]) Update(ktx kontext.Context, tx db.TX, value T) exception.Exception {
+	values := db.Values(value)
+
+	var assignments []string
+	var args []interface{}
+	for i, column := range r.columns {
+		if i == r.primaryKey {
+			continue
+		}
+
+		assignments = append(assignments, column+" = ?")
+		args = append(args, values[i])
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", r.table, strings.Join(assignments, ", "), r.config.primaryKey)
+
+	_, exc := tx.ExecContext(ktx, r.queryKey("update"), db.Rebind(tx.Dialect(), query), append(args, values[r.primaryKey])...)
+	return exc
+}
+
+// Delete row with given primary key, exception.NotFound is returned when there is no row deleted
+func (r *Repository[T]) Delete(ktx kontext.Context, tx db.TX, id interface{}) exception.Exception {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", r.table, r.config.primaryKey)
+
+	result, exc := tx.ExecContext(ktx, r.queryKey("delete"), db.Rebind(tx.Dialect(), query), id)
+	if exc != nil {
+		return exc
+	}
+
+	rowsAffected, exc := result.RowsAffected()
+	if exc != nil {
+		return exc
+	}
+
+	if rowsAffected == 0 {
+		return exception.Throw(sql.ErrNoRows, exception.WithType(exception.NotFound))
+	}
+
+	return nil
+}
+
+func (r *Repository[T]) queryKey(operation string) string {
+	return r.config.queryKeyPrefix + "." + operation
+}
+
+func whereClause(where string) string {
+	if where == "" {
+		return ""
+	}
+
+	return " WHERE " + where
+}
+
+// setInt set generated key into integer primary key field
+func setInt(pointer interface{}, id int64) exception.Exception {
+	field := reflect.ValueOf(pointer).Elem()
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(id))
+	default:
+		return exception.Throw(fmt.Errorf("generated key can not be set into %s primary key", field.Type()))
+	}
+
+	return nil
+}
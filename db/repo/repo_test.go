@@ -0,0 +1,158 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/fake"
+	"github.com/kodefluence/monorepo/db/repo"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	Email     *string   `db:"email"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+	Password  string    `db:"-"`
+}
+
+// legacyPostgres is third party dialect of database speaking postgres protocol without RETURNING support
+type legacyPostgres struct {
+	db.Dialect
+}
+
+func (legacyPostgres) Returning() bool {
+	return false
+}
+
+func TestRepository(t *testing.T) {
+	ktx := kontext.Fabricate()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	users, exc := repo.Fabricate[user]("users")
+	assert.Nil(t, exc)
+
+	t.Run("When doing crud inside transaction it will map rows into struct", func(t *testing.T) {
+		sqldb, exc := sqlite.Fabricate("repo_db", db.Config{Name: sqlite.Memory})
+		assert.Nil(t, exc)
+		defer sqldb.Eject().Close()
+
+		_, exc = sqldb.ExecContext(ktx, "users.create_table", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT, active BOOLEAN NOT NULL, created_at DATETIME NOT NULL)")
+		assert.Nil(t, exc)
+
+		email := "john@example.com"
+		john := user{Name: "john", Email: &email, Active: true, CreatedAt: createdAt}
+		jane := user{ID: 10, Name: "jane", CreatedAt: createdAt}
+
+		exc = sqldb.Transaction(ktx, "users.register", func(tx db.TX) exception.Exception {
+			if exc := users.Insert(ktx, tx, &john); exc != nil {
+				return exc
+			}
+
+			return users.Insert(ktx, tx, &jane)
+		})
+		assert.Nil(t, exc)
+		assert.Equal(t, int64(1), john.ID)
+		assert.Equal(t, int64(10), jane.ID)
+
+		found, exc := users.FindByID(ktx, sqldb, john.ID)
+		assert.Nil(t, exc)
+		assert.Equal(t, john, found)
+
+		_, exc = users.FindByID(ktx, sqldb, 99)
+		assert.Equal(t, exception.NotFound, exc.Type())
+
+		jane.Active = true
+		assert.Nil(t, users.Update(ktx, sqldb, jane))
+
+		active, exc := users.FindWhere(ktx, sqldb, "active = ?", true)
+		assert.Nil(t, exc)
+		assert.Equal(t, []user{john, jane}, active)
+
+		count, exc := users.Count(ktx, sqldb, "name = ?", "jane")
+		assert.Nil(t, exc)
+		assert.Equal(t, int64(1), count)
+
+		assert.Nil(t, users.Delete(ktx, sqldb, john.ID))
+		assert.Equal(t, exception.NotFound, users.Delete(ktx, sqldb, john.ID).Type())
+
+		count, exc = users.Count(ktx, sqldb, "")
+		assert.Nil(t, exc)
+		assert.Equal(t, int64(1), count)
+
+		all, exc := users.FindWhere(ktx, sqldb, "")
+		assert.Nil(t, exc)
+		assert.Equal(t, []user{jane}, all)
+	})
+
+	t.Run("When executing query it will use query key of the table and operation", func(t *testing.T) {
+		fakeDB := fake.Fabricate()
+		fakeDB.On("users.insert").ReturnResult(42, 1)
+		fakeDB.On("users.delete").ReturnResult(0, 1)
+		fake.ReturnStructs(fakeDB.On("users.find_by_id"), []user{{ID: 42, Name: "john"}})
+
+		john := user{Name: "john"}
+		assert.Nil(t, users.Insert(ktx, fakeDB, &john))
+		assert.Equal(t, int64(42), john.ID)
+		assert.Equal(t, "INSERT INTO users (name, email, active, created_at) VALUES (?, ?, ?, ?)", fakeDB.Queries("users.insert")[0].SQL)
+
+		found, exc := users.FindByID(ktx, fakeDB, 42)
+		assert.Nil(t, exc)
+		assert.Equal(t, "john", found.Name)
+		assert.Equal(t, "SELECT id, name, email, active, created_at FROM users WHERE id = ?", fakeDB.Queries("users.find_by_id")[0].SQL)
+
+		assert.Nil(t, users.Update(ktx, fakeDB, john))
+		assert.Equal(t, "UPDATE users SET name = ?, email = ?, active = ?, created_at = ? WHERE id = ?", fakeDB.Queries("users.update")[0].SQL)
+		assert.Equal(t, int64(42), fakeDB.Queries("users.update")[0].Args[4])
+
+		assert.Nil(t, users.Delete(ktx, fakeDB, 42))
+
+		_, _ = users.FindWhere(ktx, fakeDB, "active = ?", true)
+		_, _ = users.Count(ktx, fakeDB, "")
+		assert.True(t, fakeDB.Executed("users.find_where"))
+		assert.True(t, fakeDB.Executed("users.count"))
+	})
+
+	t.Run("When using postgres it will rebind placeholders and return generated key", func(t *testing.T) {
+		accounts, exc := repo.Fabricate[user]("accounts", repo.WithQueryKeyPrefix("account"))
+		assert.Nil(t, exc)
+
+		fakeDB := fake.Fabricate(fake.WithDialect(db.Postgres))
+		fakeDB.On("account.insert").ReturnRows([]string{"id"}, []interface{}{int64(7)})
+
+		john := user{Name: "john"}
+		assert.Nil(t, accounts.Insert(ktx, fakeDB, &john))
+		assert.Equal(t, int64(7), john.ID)
+		assert.Equal(t, "INSERT INTO accounts (name, email, active, created_at) VALUES ($1, $2, $3, $4) RETURNING id", fakeDB.Queries("account.insert")[0].SQL)
+
+		_, _ = accounts.FindWhere(ktx, fakeDB, "name = ? AND active = ?", "john", true)
+		assert.Equal(t, "SELECT id, name, email, active, created_at FROM accounts WHERE name = $1 AND active = $2 ORDER BY id", fakeDB.Queries("account.find_where")[0].SQL)
+	})
+
+	t.Run("When dialect does not support returning it will read generated key using last insert id", func(t *testing.T) {
+		accounts, exc := repo.Fabricate[user]("accounts", repo.WithQueryKeyPrefix("account"))
+		assert.Nil(t, exc)
+
+		fakeDB := fake.Fabricate(fake.WithDialect(legacyPostgres{Dialect: db.Postgres}))
+		fakeDB.On("account.insert").ReturnResult(8, 1)
+
+		john := user{Name: "john"}
+		assert.Nil(t, accounts.Insert(ktx, fakeDB, &john))
+		assert.Equal(t, int64(8), john.ID)
+		assert.Equal(t, "INSERT INTO accounts (name, email, active, created_at) VALUES ($1, $2, $3, $4)", fakeDB.Queries("account.insert")[0].SQL)
+	})
+
+	t.Run("When the type is not mapped it will return bad input exception", func(t *testing.T) {
+		_, exc := repo.Fabricate[int]("users")
+		assert.Equal(t, exception.BadInput, exc.Type())
+
+		_, exc = repo.Fabricate[user]("users", repo.WithPrimaryKey("uuid"))
+		assert.Equal(t, exception.BadInput, exc.Type())
+	})
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kodefluence/monorepo/db/internal/backoff"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// RetryConfig carry transaction retry policy
+type RetryConfig struct {
+	maxAttempts    int
+	keyMaxAttempts map[string]int
+	baseBackoff    time.Duration
+	maxBackoff     time.Duration
+}
+
+// RetryOption when configuring transaction retry policy
+type RetryOption func(*RetryConfig)
+
+// WithRetryMaxAttempts set maximum attempts of a transaction including the first one, default to 3
+func WithRetryMaxAttempts(maxAttempts int) RetryOption {
+	return func(c *RetryConfig) {
+		c.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetryKeyMaxAttempts set maximum attempts for specific transactionKey, set it into 1 to disable retry for that transaction
+func WithRetryKeyMaxAttempts(transactionKey string, maxAttempts int) RetryOption {
+	return func(c *RetryConfig) {
+		c.keyMaxAttempts[transactionKey] = maxAttempts
+	}
+}
+
+// WithRetryBackoff set exponential backoff base and its cap, default to 10 milliseconds and 1 second
+func WithRetryBackoff(baseBackoff, maxBackoff time.Duration) RetryOption {
+	return func(c *RetryConfig) {
+		c.baseBackoff = baseBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithTransactionRetry adapt connection with transaction retry policy.
+// Transaction closure is re-run when it fail with driver error reported by Dialect Retryable, such as deadlock or lock wait timeout.
+// Exception.Conflict which is not caused by such driver error, for example returned by the closure itself or by Lock timeout, is not retried.
+func WithTransactionRetry(opts ...RetryOption) Option {
+	config := RetryConfig{
+		maxAttempts:    3,
+		keyMaxAttempts: map[string]int{},
+		baseBackoff:    10 * time.Millisecond,
+		maxBackoff:     time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(c *Config) {
+		c.retry = &config
+	}
+}
+
+func (r *RetryConfig) maxAttemptsOf(transactionKey string) int {
+	if r == nil {
+		return 1
+	}
+
+	if maxAttempts, ok := r.keyMaxAttempts[transactionKey]; ok {
+		return maxAttempts
+	}
+
+	return r.maxAttempts
+}
+
+func retryTransaction(ktx kontext.Context, retry *RetryConfig, dialect Dialect, transactionKey string, f func(attempt int) exception.Exception) exception.Exception {
+	maxAttempts := retry.maxAttemptsOf(transactionKey)
+
+	var exc exception.Exception
+	for attempt := 1; ; attempt++ {
+		exc = f(attempt)
+		if exc == nil || !dialect.Retryable(exc) || attempt >= maxAttempts {
+			return exc
+		}
+
+		// The first retry is the second attempt
+		timer := time.NewTimer(backoff.Exponential(retry.baseBackoff, retry.maxBackoff, attempt))
+		select {
+		case <-ktx.Ctx().Done():
+			timer.Stop()
+
+			// Keep the last transaction exception so the caller still see why it was retried
+			return exception.Throw(fmt.Errorf("%w: retry is aborted: %w", exc, ktx.Ctx().Err()), exception.WithType(exc.Type()), exception.WithTitle(exc.Title()), exception.WithDetail(exc.Detail()))
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionRetry(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When transaction hit deadlock it will be retried until it succeed", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`update orders`).WillReturnError(&mysql.MySQLError{Number: 1213})
+		mockDB.ExpectRollback()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`update orders`).WillReturnError(&mysql.MySQLError{Number: 1205})
+		mockDB.ExpectRollback()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`update orders`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		analyzer := &recordAnalyzer{}
+		sql := db.Adapt(sqldb, db.WithAnalyzer(analyzer), db.WithTransactionRetry(db.WithRetryBackoff(time.Millisecond, 2*time.Millisecond)))
+
+		runs := 0
+		exc := sql.Transaction(ktx, "pay-order", func(tx db.TX) exception.Exception {
+			runs++
+			_, exc := tx.ExecContext(ktx, "update-order", "update orders")
+			return exc
+		})
+		assert.Nil(t, exc)
+		assert.Equal(t, 3, runs)
+
+		var attempts []int
+		for _, info := range analyzer.after {
+			if info.Function == "Transaction" {
+				attempts = append(attempts, info.Attempt)
+			}
+		}
+		assert.Equal(t, []int{1, 2, 3}, attempts)
+		assert.Equal(t, 3, analyzer.after[4].Attempt)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When transaction keep failing it will return the last exception after max attempts", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		for i := 0; i < 2; i++ {
+			mockDB.ExpectBegin()
+			mockDB.ExpectExec(`update orders`).WillReturnError(&mysql.MySQLError{Number: 1213})
+			mockDB.ExpectRollback()
+		}
+
+		sql := db.Adapt(sqldb, db.WithTransactionRetry(db.WithRetryMaxAttempts(5), db.WithRetryKeyMaxAttempts("pay-order", 2), db.WithRetryBackoff(time.Millisecond, time.Millisecond)))
+		exc := sql.Transaction(ktx, "pay-order", func(tx db.TX) exception.Exception {
+			_, exc := tx.ExecContext(ktx, "update-order", "update orders")
+			return exc
+		})
+		assert.Equal(t, exception.Conflict, exc.Type())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When transaction failed because of other than conflict it will not be retried", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`insert into orders`).WillReturnError(&mysql.MySQLError{Number: 1062})
+		mockDB.ExpectRollback()
+
+		sql := db.Adapt(sqldb, db.WithTransactionRetry())
+		exc := sql.Transaction(ktx, "create-order", func(tx db.TX) exception.Exception {
+			_, exc := tx.ExecContext(ktx, "insert-order", "insert into orders")
+			return exc
+		})
+		assert.Equal(t, exception.Duplicated, exc.Type())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When conflict is not caused by the database it will not be retried", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		lockDB, lockMock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer lockDB.Close()
+
+		for i := 0; i < 2; i++ {
+			mockDB.ExpectBegin()
+			mockDB.ExpectRollback()
+		}
+		lockMock.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"database"}).AddRow(nil))
+		lockMock.ExpectQuery(`SELECT GET_LOCK`).WithArgs("order-1", 0).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+		sql := db.Adapt(sqldb, db.WithTransactionRetry(db.WithRetryBackoff(time.Millisecond, time.Millisecond)))
+		locker := db.Adapt(lockDB)
+
+		runs := 0
+		exc := sql.Transaction(ktx, "reserve-stock", func(tx db.TX) exception.Exception {
+			runs++
+			return exception.Throw(errors.New("stock is reserved"), exception.WithType(exception.Conflict))
+		})
+		assert.Equal(t, exception.Conflict, exc.Type())
+		assert.Equal(t, 1, runs)
+
+		runs = 0
+		exc = sql.Transaction(ktx, "pay-order", func(tx db.TX) exception.Exception {
+			runs++
+			_, exc := locker.Lock(ktx, "order-1", 0)
+			return exc
+		})
+		assert.Equal(t, "lock timeout", exc.Title())
+		assert.Equal(t, 1, runs)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Nil(t, lockMock.ExpectationsWereMet())
+	})
+
+	t.Run("When kontext cancelled while waiting for backoff it will stop retrying and return the last exception", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`update orders`).WillReturnError(&mysql.MySQLError{Number: 1213})
+		mockDB.ExpectRollback()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancellableKtx := kontext.Fabricate(kontext.WithDefaultContext(ctx))
+
+		sql := db.Adapt(sqldb, db.WithTransactionRetry(db.WithRetryBackoff(time.Minute, time.Minute)))
+		exc := sql.Transaction(cancellableKtx, "pay-order", func(tx db.TX) exception.Exception {
+			_, exc := tx.ExecContext(cancellableKtx, "update-order", "update orders")
+			cancel()
+			return exc
+		})
+		assert.Equal(t, exception.Conflict, exc.Type())
+		assert.ErrorIs(t, exc, context.Canceled)
+
+		var mysqlErr *mysql.MySQLError
+		assert.ErrorAs(t, exc, &mysqlErr)
+		assert.Equal(t, uint16(1213), mysqlErr.Number)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
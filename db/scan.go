@@ -0,0 +1,301 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// ScanOne scan the first row of rows into T and close the rows, it return exception.NotFound when there is no row.
+// See ScanAll for how columns is mapped into T.
+func ScanOne[T any](rows Rows) (T, exception.Exception) {
+	var result T
+
+	results, exc := scanRows[T](rows, 1)
+	if exc != nil {
+		return result, exc
+	}
+
+	if len(results) == 0 {
+		return result, exception.Throw(sql.ErrNoRows, exception.WithType(exception.NotFound))
+	}
+
+	return results[0], nil
+}
+
+// ScanAll scan every row of rows into slice of T and close the rows, it return empty slice when there is no row.
+//
+// When T is a struct, column is mapped into field based on `db:"column"` tag, field without tag is mapped by its snake case name and `db:"-"` is skipped.
+// Fields of embedded struct is mapped as if it is declared in T, pointer field is set to nil on NULL value and field implementing sql.Scanner is scanned as it is.
+// Column without matching field is ignored. When T is not a struct, rows must have a single column.
+func ScanAll[T any](rows Rows) ([]T, exception.Exception) {
+	return scanRows[T](rows, -1)
+}
+
+// ScanRow scan single row into T, since Row does not carry columns information the query must select Columns of T in the same order
+func ScanRow[T any](row Row) (T, exception.Exception) {
+	var result T
+
+	value := reflect.ValueOf(&result).Elem()
+	mapping := mappingOf(value.Type())
+	if mapping == nil {
+		return result, row.Scan(&result)
+	}
+
+	dest := make([]interface{}, len(mapping.fields))
+	for i, field := range mapping.fields {
+		dest[i] = fieldByIndex(value, field.index).Addr().Interface()
+	}
+
+	return result, row.Scan(dest...)
+}
+
+// Columns return mapped columns of struct T ordered by its field declaration
+func Columns[T any]() []string {
+	var t T
+
+	mapping := mappingOf(reflect.TypeOf(&t).Elem())
+	if mapping == nil {
+		return nil
+	}
+
+	columns := make([]string, len(mapping.fields))
+	for i, field := range mapping.fields {
+		columns[i] = field.column
+	}
+
+	return columns
+}
+
+// Values return field values of struct T ordered the same as Columns, field inside nil embedded pointer is returned as nil
+func Values[T any](value T) []interface{} {
+	v := reflect.ValueOf(&value).Elem()
+
+	mapping := mappingOf(v.Type())
+	if mapping == nil {
+		return nil
+	}
+
+	values := make([]interface{}, len(mapping.fields))
+	for i, field := range mapping.fields {
+		if fieldValue, ok := valueByIndex(v, field.index); ok {
+			values[i] = fieldValue.Interface()
+		}
+	}
+
+	return values
+}
+
+// Pointers return pointer of fields of struct value ordered the same as Columns, nil embedded pointer is allocated so the pointers can be passed into Scan
+func Pointers[T any](value *T) []interface{} {
+	v := reflect.ValueOf(value).Elem()
+
+	mapping := mappingOf(v.Type())
+	if mapping == nil {
+		return nil
+	}
+
+	pointers := make([]interface{}, len(mapping.fields))
+	for i, field := range mapping.fields {
+		pointers[i] = fieldByIndex(v, field.index).Addr().Interface()
+	}
+
+	return pointers
+}
+
+func scanRows[T any](rows Rows, limit int) ([]T, exception.Exception) {
+	defer rows.Close()
+
+	columns, exc := rows.Columns()
+	if exc != nil {
+		return nil, exc
+	}
+
+	mapping := mappingOf(reflect.TypeOf((*T)(nil)).Elem())
+	if mapping == nil && len(columns) != 1 {
+		return nil, exception.Throw(fmt.Errorf("scanning %d columns into non struct type %T", len(columns), *new(T)), exception.WithType(exception.BadInput))
+	}
+
+	results := []T{}
+	for (limit < 0 || len(results) < limit) && rows.Next() {
+		var result T
+
+		if mapping == nil {
+			if exc := rows.Scan(&result); exc != nil {
+				return nil, exc
+			}
+		} else {
+			value := reflect.ValueOf(&result).Elem()
+
+			dest := make([]interface{}, len(columns))
+			for i, column := range columns {
+				if field, ok := mapping.byColumn[column]; ok {
+					dest[i] = fieldByIndex(value, field.index).Addr().Interface()
+				} else {
+					dest[i] = new(interface{})
+				}
+			}
+
+			if exc := rows.Scan(dest...); exc != nil {
+				return nil, exc
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	if exc := rows.Err(); exc != nil {
+		return nil, exc
+	}
+
+	return results, nil
+}
+
+type structField struct {
+	column string
+	index  []int
+}
+
+type structMapping struct {
+	fields   []structField
+	byColumn map[string]structField
+}
+
+var (
+	structMappings = &sync.Map{}
+	scannerType    = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// mappingOf return column mapping of struct type, it return nil when the type should be scanned as single column
+func mappingOf(t reflect.Type) *structMapping {
+	if isScalar(t) {
+		return nil
+	}
+
+	if val, ok := structMappings.Load(t); ok {
+		return val.(*structMapping)
+	}
+
+	mapping := &structMapping{byColumn: map[string]structField{}}
+	collectFields(t, nil, mapping)
+	structMappings.Store(t, mapping)
+
+	return mapping
+}
+
+// collectFields map exported fields of t into columns, field of embedded struct is promoted the same as Go so shallower field win the column.
+// Embedded pointer to unexported struct type is skipped since reflection can not allocate nor read through it.
+func collectFields(t reflect.Type, parentIndex []int, mapping *structMapping) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		index := append(append([]int{}, parentIndex...), i)
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && !tagged && !isScalar(fieldType) {
+			if field.Type.Kind() == reflect.Ptr && !field.IsExported() {
+				continue
+			}
+
+			collectFields(fieldType, index, mapping)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		column := tag
+		if !tagged || column == "" {
+			column = toSnakeCase(field.Name)
+		}
+
+		structField := structField{column: column, index: index}
+
+		if existing, exists := mapping.byColumn[column]; exists {
+			if len(existing.index) <= len(index) {
+				continue
+			}
+
+			for j := range mapping.fields {
+				if mapping.fields[j].column == column {
+					mapping.fields[j] = structField
+				}
+			}
+			mapping.byColumn[column] = structField
+			continue
+		}
+
+		mapping.fields = append(mapping.fields, structField)
+		mapping.byColumn[column] = structField
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType)
+}
+
+// fieldByIndex return settable field of nested index, allocating nil embedded struct pointer along the way
+func fieldByIndex(value reflect.Value, index []int) reflect.Value {
+	for i, position := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+
+		value = value.Field(position)
+	}
+
+	return value
+}
+
+// valueByIndex return field of nested index without allocating, it return false when the field is inside nil embedded struct pointer
+func valueByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, position := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+
+		value = value.Field(position)
+	}
+
+	return value, true
+}
+
+func toSnakeCase(name string) string {
+	var builder strings.Builder
+
+	runes := []rune(name)
+	for i, char := range runes {
+		if unicode.IsUpper(char) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				builder.WriteByte('_')
+			}
+			char = unicode.ToLower(char)
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,217 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type Timestamp struct {
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+type scannedUser struct {
+	ID       int64          `db:"id"`
+	Name     string         `db:"full_name"`
+	Nickname *string        `db:"nickname"`
+	Email    sql.NullString `db:"email"`
+	Password string         `db:"-"`
+	*Timestamp
+}
+
+type auditTrail struct {
+	UpdatedBy string
+}
+
+type shadowingUser struct {
+	*Timestamp
+	CreatedAt string `db:"created_at"`
+	*auditTrail
+}
+
+func TestScan(t *testing.T) {
+	ktx := kontext.Fabricate()
+	now := time.Now()
+
+	t.Run("Columns", func(t *testing.T) {
+		assert.Equal(t, []string{"id", "full_name", "nickname", "email", "created_at", "updated_at"}, db.Columns[scannedUser]())
+		assert.Nil(t, db.Columns[int]())
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		nickname := "john"
+		assert.Equal(t, []interface{}{int64(1), "John Doe", &nickname, sql.NullString{}, nil, nil}, db.Values(scannedUser{ID: 1, Name: "John Doe", Nickname: &nickname}))
+		assert.Equal(t, now, db.Values(scannedUser{Timestamp: &Timestamp{CreatedAt: now}})[4])
+		assert.Nil(t, db.Values(1))
+	})
+
+	t.Run("Pointers", func(t *testing.T) {
+		var user scannedUser
+		pointers := db.Pointers(&user)
+		assert.Len(t, pointers, 6)
+
+		*pointers[0].(*int64) = 1
+		*pointers[4].(*time.Time) = now
+		assert.Equal(t, int64(1), user.ID)
+		assert.Equal(t, now, user.CreatedAt)
+
+		value := 1
+		assert.Nil(t, db.Pointers(&value))
+	})
+
+	t.Run("When embedded struct has the same column as outer field it will be shadowed by the outer field", func(t *testing.T) {
+		assert.Equal(t, []string{"created_at", "updated_at"}, db.Columns[shadowingUser]())
+
+		var user shadowingUser
+		pointers := db.Pointers(&user)
+		assert.Len(t, pointers, 2)
+
+		*pointers[0].(*string) = "yesterday"
+		assert.Equal(t, "yesterday", user.CreatedAt)
+	})
+
+	t.Run("When embedded pointer has unexported struct type it will be skipped", func(t *testing.T) {
+		var user shadowingUser
+		assert.NotPanics(t, func() { db.Pointers(&user) })
+		assert.NotContains(t, db.Columns[shadowingUser](), "updated_by")
+		assert.Nil(t, user.auditTrail)
+	})
+
+	t.Run("ScanAll", func(t *testing.T) {
+		t.Run("When there is rows it will be mapped into struct by column name", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"full_name", "id", "nickname", "email", "created_at", "updated_at", "unknown"}).
+				AddRow("John Doe", 1, "john", "john@example.com", now, nil, "ignored").
+				AddRow("Jane Doe", 2, nil, nil, now, now, "ignored"))
+
+			rows, exc := db.Adapt(sqldb).QueryContext(ktx, "list-users", "select")
+			assert.Nil(t, exc)
+
+			users, exc := db.ScanAll[scannedUser](rows)
+			assert.Nil(t, exc)
+			assert.Equal(t, 2, len(users))
+
+			assert.Equal(t, int64(1), users[0].ID)
+			assert.Equal(t, "John Doe", users[0].Name)
+			assert.Equal(t, "john", *users[0].Nickname)
+			assert.Equal(t, sql.NullString{String: "john@example.com", Valid: true}, users[0].Email)
+			assert.Equal(t, now, users[0].CreatedAt)
+			assert.Nil(t, users[0].UpdatedAt)
+
+			assert.Nil(t, users[1].Nickname)
+			assert.False(t, users[1].Email.Valid)
+			assert.Equal(t, now, *users[1].UpdatedAt)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+
+		t.Run("When there is no rows it will return empty slice", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			rows, exc := db.Adapt(sqldb).QueryContext(ktx, "list-users", "select")
+			assert.Nil(t, exc)
+
+			ids, exc := db.ScanAll[int64](rows)
+			assert.Nil(t, exc)
+			assert.Equal(t, []int64{}, ids)
+		})
+
+		t.Run("When scanning multiple columns into non struct it will return bad input exception", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "john"))
+
+			rows, exc := db.Adapt(sqldb).QueryContext(ktx, "list-users", "select")
+			assert.Nil(t, exc)
+
+			_, exc = db.ScanAll[int64](rows)
+			assert.Equal(t, exception.BadInput, exc.Type())
+		})
+	})
+
+	t.Run("ScanOne", func(t *testing.T) {
+		t.Run("When there is rows it will return the first one", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"id", "full_name"}).AddRow(1, "John Doe").AddRow(2, "Jane Doe"))
+
+			rows, exc := db.Adapt(sqldb).QueryContext(ktx, "find-user", "select")
+			assert.Nil(t, exc)
+
+			user, exc := db.ScanOne[scannedUser](rows)
+			assert.Nil(t, exc)
+			assert.Equal(t, "John Doe", user.Name)
+		})
+
+		t.Run("When there is no rows it will return not found exception", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"id", "full_name"}))
+
+			rows, exc := db.Adapt(sqldb).QueryContext(ktx, "find-user", "select")
+			assert.Nil(t, exc)
+
+			_, exc = db.ScanOne[scannedUser](rows)
+			assert.Equal(t, exception.NotFound, exc.Type())
+		})
+	})
+
+	t.Run("ScanRow", func(t *testing.T) {
+		t.Run("When row found it will be mapped into struct by columns order", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows(db.Columns[scannedUser]()).AddRow(1, "John Doe", nil, nil, now, nil))
+
+			user, exc := db.ScanRow[scannedUser](db.Adapt(sqldb).QueryRowContext(ktx, "find-user", "select"))
+			assert.Nil(t, exc)
+			assert.Equal(t, int64(1), user.ID)
+			assert.Equal(t, now, user.CreatedAt)
+		})
+
+		t.Run("When row not found it will return not found exception", func(t *testing.T) {
+			sqldb, mockDB, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer sqldb.Close()
+
+			mockDB.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"count"}))
+
+			_, exc := db.ScanRow[int](db.Adapt(sqldb).QueryRowContext(ktx, "count-user", "select"))
+			assert.Equal(t, exception.NotFound, exc.Type())
+		})
+	})
+}
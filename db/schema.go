@@ -0,0 +1,70 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnType is portable column type translated into database type by Dialect CreateTable
+type ColumnType int
+
+const (
+	// AutoIncrement is 64 bit integer primary key generated by the database
+	AutoIncrement ColumnType = iota
+	// String is text limited to 255 characters so it can be indexed in every database
+	String
+	// Text is unlimited text
+	Text
+	// Bytes is unlimited binary data
+	Bytes
+	// Integer is 32 bit integer
+	Integer
+	// Timestamp is date and time with at least microsecond precision
+	Timestamp
+)
+
+// Table definition used by Dialect CreateTable
+type Table struct {
+	Name    string
+	Columns []Column
+	Indexes []Index
+}
+
+// Column of table, it is NOT NULL unless Null is set and Default is written into DEFAULT clause as it is
+type Column struct {
+	Name    string
+	Type    ColumnType
+	Null    bool
+	Default string
+}
+
+// Index of table columns
+type Index struct {
+	Name    string
+	Columns []string
+}
+
+// Definition of the column using database type, AutoIncrement type is expected to declare the primary key itself
+func (c Column) Definition(databaseType string) string {
+	definition := c.Name + " " + databaseType
+	if c.Type == AutoIncrement {
+		return definition
+	}
+
+	if c.Null {
+		definition += " NULL"
+	} else {
+		definition += " NOT NULL"
+	}
+
+	if c.Default != "" {
+		definition += " DEFAULT " + c.Default
+	}
+
+	return definition
+}
+
+// Create return statement creating the index on table when it does not exist yet, used by dialect supporting CREATE INDEX IF NOT EXISTS
+func (i Index) Create(table string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", i.Name, table, strings.Join(i.Columns, ", "))
+}
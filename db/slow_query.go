@@ -0,0 +1,124 @@
+package db
+
+import (
+	"time"
+
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// SlowQuery carry information of query or transaction which exceeding the threshold
+type SlowQuery struct {
+	ExecutionLevel string
+	Function       string
+	TransactionKey string
+	Key            string
+	SQL            string
+	Elapsed        time.Duration
+	Threshold      time.Duration
+
+	// RequestIdentifiers collected from kontext based on configured request identifier keys, missing value is not included
+	RequestIdentifiers map[string]interface{}
+}
+
+// SlowQueryReporter receive every slow query detected
+type SlowQueryReporter func(ktx kontext.Context, slowQuery SlowQuery)
+
+// SlowQueryConfig carry slow query detector config
+type SlowQueryConfig struct {
+	threshold             time.Duration
+	keyThresholds         map[string]time.Duration
+	requestIdentifierKeys []string
+}
+
+// SlowQueryOption when fabricating slow query detector
+type SlowQueryOption func(*SlowQueryConfig)
+
+// WithSlowQueryThreshold set global threshold, default to 1 second
+func WithSlowQueryThreshold(threshold time.Duration) SlowQueryOption {
+	return func(c *SlowQueryConfig) {
+		c.threshold = threshold
+	}
+}
+
+// WithSlowQueryKeyThreshold set threshold for specific queryKey or transactionKey, it take precedence over global threshold
+func WithSlowQueryKeyThreshold(key string, threshold time.Duration) SlowQueryOption {
+	return func(c *SlowQueryConfig) {
+		c.keyThresholds[key] = threshold
+	}
+}
+
+// WithRequestIdentifierKeys set kontext keys reported as request identifiers, default to "request_id"
+func WithRequestIdentifierKeys(keys ...string) SlowQueryOption {
+	return func(c *SlowQueryConfig) {
+		c.requestIdentifierKeys = keys
+	}
+}
+
+// SlowQueryDetector is an Analyzer which report every query or transaction exceeding the threshold
+type SlowQueryDetector struct {
+	config   SlowQueryConfig
+	reporter SlowQueryReporter
+}
+
+// FabricateSlowQueryDetector fabricate slow query detector
+func FabricateSlowQueryDetector(reporter SlowQueryReporter, opts ...SlowQueryOption) *SlowQueryDetector {
+	var config SlowQueryConfig
+
+	// Default value
+	config.threshold = time.Second
+	config.keyThresholds = map[string]time.Duration{}
+	config.requestIdentifierKeys = []string{"request_id"}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &SlowQueryDetector{config: config, reporter: reporter}
+}
+
+// WithSlowQueryLog adapt connection with slow query detector
+func WithSlowQueryLog(reporter SlowQueryReporter, opts ...SlowQueryOption) Option {
+	return WithAnalyzer(FabricateSlowQueryDetector(reporter, opts...))
+}
+
+// Threshold return threshold applied for given key
+func (s *SlowQueryDetector) Threshold(key string) time.Duration {
+	if threshold, ok := s.config.keyThresholds[key]; ok {
+		return threshold
+	}
+
+	return s.config.threshold
+}
+
+// Before do nothing, slow query only detected after the execution
+func (s *SlowQueryDetector) Before(ktx kontext.Context, info QueryInfo) {}
+
+// After report the query if it is exceeding the threshold, Lock is not reported since waiting for the lock is expected
+func (s *SlowQueryDetector) After(ktx kontext.Context, info QueryInfo) {
+	if info.Function == "Lock" {
+		return
+	}
+
+	threshold := s.Threshold(info.Key)
+	if info.Duration <= threshold {
+		return
+	}
+
+	requestIdentifiers := map[string]interface{}{}
+	for _, key := range s.config.requestIdentifierKeys {
+		if val, ok := ktx.Get(key); ok {
+			requestIdentifiers[key] = val
+		}
+	}
+
+	s.reporter(ktx, SlowQuery{
+		ExecutionLevel:     info.ExecutionLevel,
+		Function:           info.Function,
+		TransactionKey:     info.TransactionKey,
+		Key:                info.Key,
+		SQL:                info.SQL,
+		Elapsed:            info.Duration,
+		Threshold:          threshold,
+		RequestIdentifiers: requestIdentifiers,
+	})
+}
@@ -0,0 +1,71 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQueryDetector(t *testing.T) {
+	t.Run("When query exceeding threshold it will be reported with request identifiers", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectExec(`delete from users`).WillDelayFor(10 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectExec(`delete from sessions`).WillDelayFor(10 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ktx := kontext.Fabricate()
+		ktx.Set("request_id", "abc-123")
+
+		var slowQueries []db.SlowQuery
+		sql := db.Adapt(sqldb, db.WithSlowQueryLog(func(ktx kontext.Context, slowQuery db.SlowQuery) {
+			slowQueries = append(slowQueries, slowQuery)
+		}, db.WithSlowQueryThreshold(time.Millisecond), db.WithSlowQueryKeyThreshold("delete-sessions", time.Minute)))
+
+		_, exc := sql.ExecContext(ktx, "delete-users", "delete from users")
+		assert.Nil(t, exc)
+		_, exc = sql.ExecContext(ktx, "delete-sessions", "delete from sessions")
+		assert.Nil(t, exc)
+
+		assert.Equal(t, 1, len(slowQueries))
+		assert.Equal(t, "delete-users", slowQueries[0].Key)
+		assert.Equal(t, time.Millisecond, slowQueries[0].Threshold)
+		assert.True(t, slowQueries[0].Elapsed > time.Millisecond)
+		assert.Equal(t, map[string]interface{}{"request_id": "abc-123"}, slowQueries[0].RequestIdentifiers)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When waiting for lock exceeding threshold it will not be reported", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"database"}).AddRow(nil))
+		mockDB.ExpectQuery(`SELECT GET_LOCK`).WillDelayFor(10 * time.Millisecond).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+		var slowQueries []db.SlowQuery
+		sql := db.Adapt(sqldb, db.WithSlowQueryLog(func(ktx kontext.Context, slowQuery db.SlowQuery) {
+			slowQueries = append(slowQueries, slowQuery)
+		}, db.WithSlowQueryThreshold(time.Millisecond)))
+
+		_, exc := sql.Lock(kontext.Fabricate(), "daily-report", 0)
+		assert.NotNil(t, exc)
+		assert.Equal(t, 0, len(slowQueries))
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Threshold", func(t *testing.T) {
+		detector := db.FabricateSlowQueryDetector(func(ktx kontext.Context, slowQuery db.SlowQuery) {}, db.WithSlowQueryKeyThreshold("report-query", time.Minute))
+		assert.Equal(t, time.Second, detector.Threshold("any-query"))
+		assert.Equal(t, time.Minute, detector.Threshold("report-query"))
+	})
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kodefluence/monorepo/kontext"
+)
+
+// SQLCommentConfig carry sql comment tagging config
+type SQLCommentConfig struct {
+	service     string
+	kontextKeys []string
+}
+
+// SQLCommentOption when configuring sql comment tagging
+type SQLCommentOption func(*SQLCommentConfig)
+
+// WithSQLCommentService tag every query with given service name
+func WithSQLCommentService(service string) SQLCommentOption {
+	return func(c *SQLCommentConfig) {
+		c.service = service
+	}
+}
+
+// WithSQLCommentKontextKeys set kontext keys tagged into the comment, default to "request_id", missing value is not included
+func WithSQLCommentKontextKeys(keys ...string) SQLCommentOption {
+	return func(c *SQLCommentConfig) {
+		c.kontextKeys = keys
+	}
+}
+
+// WithSQLComment adapt connection to prepend sqlcommenter formatted comment into every query, for example:
+//
+//	/*queryKey='users.find_by_id',request_id='8f14e45f',service='account'*/ SELECT ...
+//
+// So the query can be traced back from processlist or performance_schema. Analyzers still receive the original query.
+// Kontext values is not tagged when statement cache is enabled since it would prepare new statement on every request.
+func WithSQLComment(opts ...SQLCommentOption) Option {
+	config := SQLCommentConfig{
+		kontextKeys: []string{"request_id"},
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(c *Config) {
+		c.sqlComment = &config
+	}
+}
+
+// commented prepend sql comment into the query if it is enabled
+func (c Config) commented(ktx kontext.Context, queryKey, query string) string {
+	if c.sqlComment == nil {
+		return query
+	}
+
+	tags := map[string]string{}
+	if queryKey != "" {
+		tags["queryKey"] = queryKey
+	}
+
+	if c.sqlComment.service != "" {
+		tags["service"] = c.sqlComment.service
+	}
+
+	if c.statementCacheSize <= 0 {
+		for _, key := range c.sqlComment.kontextKeys {
+			if val, ok := ktx.Get(key); ok && val != nil {
+				tags[key] = fmt.Sprint(val)
+			}
+		}
+	}
+
+	if len(tags) == 0 {
+		return query
+	}
+
+	return sqlComment(tags) + " " + query
+}
+
+// sqlComment serialize tags based on sqlcommenter spec, key and value is url encoded, value is quoted and tags is sorted by its key
+func sqlComment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	serialized := make([]string, 0, len(keys))
+	for _, key := range keys {
+		serialized = append(serialized, fmt.Sprintf("%s='%s'", sqlCommentEscape(key), sqlCommentEscape(tags[key])))
+	}
+
+	return "/*" + strings.Join(serialized, ",") + "*/"
+}
+
+func sqlCommentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,99 @@
+package db_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedQuery struct {
+	sql string
+}
+
+func (r *recordedQuery) Before(ktx kontext.Context, info db.QueryInfo) {
+	if info.SQL != "" {
+		r.sql = info.SQL
+	}
+}
+
+func (r *recordedQuery) After(ktx kontext.Context, info db.QueryInfo) {}
+
+func TestSQLComment(t *testing.T) {
+	t.Run("When sql comment is enabled it will prepend queryKey, kontext values and service into the query", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		ktx := kontext.Fabricate()
+		ktx.Set("request_id", "8f14e45f ceea*/")
+		ktx.Set("tenant_id", 10)
+
+		mockDB.ExpectExec(`/*queryKey='users.update',request_id='8f14e45f%20ceea%2A%2F',service='account',tenant_id='10'*/ update users set name = ?`).WithArgs("john").WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`/*queryKey='users.list',request_id='8f14e45f%20ceea%2A%2F',service='account',tenant_id='10'*/ select id from users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mockDB.ExpectQuery(`/*queryKey='users.find',request_id='8f14e45f%20ceea%2A%2F',service='account',tenant_id='10'*/ select id from users where id = ?`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mockDB.ExpectCommit()
+
+		analyzer := &recordedQuery{}
+		adapter := db.Adapt(sqldb, db.WithAnalyzer(analyzer), db.WithSQLComment(db.WithSQLCommentService("account"), db.WithSQLCommentKontextKeys("request_id", "tenant_id", "user_id")))
+
+		_, exc := adapter.ExecContext(ktx, "users.update", "update users set name = ?", "john")
+		assert.Nil(t, exc)
+		assert.Equal(t, "update users set name = ?", analyzer.sql)
+
+		exc = adapter.Transaction(ktx, "users.list", func(tx db.TX) exception.Exception {
+			rows, exc := tx.QueryContext(ktx, "users.list", "select id from users")
+			if exc != nil {
+				return exc
+			}
+			_ = rows.Close()
+
+			var id int
+			return tx.QueryRowContext(ktx, "users.find", "select id from users where id = ?", 1).Scan(&id)
+		})
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When statement cache is enabled it will not tag kontext values", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectPrepare(regexp.QuoteMeta(`/*queryKey='users.update'*/ update users set name = ?`)).ExpectExec().WithArgs("john").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ktx := kontext.Fabricate()
+		ktx.Set("request_id", "8f14e45f")
+
+		adapter := db.Adapt(sqldb, db.WithStatementCache(10), db.WithSQLComment())
+		_, exc := adapter.ExecContext(ktx, "users.update", "update users set name = ?", "john")
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When sql comment is disabled it will execute the query as it is", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectExec(`update users set name = ?`).WithArgs("john").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ktx := kontext.Fabricate()
+		ktx.Set("request_id", "8f14e45f")
+
+		_, exc := db.Adapt(sqldb).ExecContext(ktx, "users.update", "update users set name = ?", "john")
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type dialect struct{}
+
+func (dialect) Name() string {
+	return "sqlite"
+}
+
+func (dialect) DriverName() string {
+	return "sqlite"
+}
+
+// DSN of sqlite use config name as database file path, only params is added and other config is ignored
+func (dialect) DSN(config db.Config) (string, exception.Exception) {
+	dsn := config.Name
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	dsn += separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	params := config.Params()
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		dsn += "&" + url.QueryEscape(key) + "=" + url.QueryEscape(params[key])
+	}
+
+	return dsn, nil
+}
+
+func (dialect) Placeholder(position int) string {
+	return "?"
+}
+
+// Translate sqlite error based on extended result code, see https://www.sqlite.org/rescode.html
+func (dialect) Translate(err error) exception.Exception {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return db.TranslateGeneric(err)
+	}
+
+	detail := exception.WithDetail(fmt.Sprintf("sqlite error code: %d", sqliteErr.Code()))
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return exception.Throw(err, exception.WithType(exception.Duplicated), exception.WithTitle("duplicated data"), detail)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY, sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return exception.Throw(err, exception.WithType(exception.BadInput), exception.WithTitle("invalid data"), detail)
+	}
+
+	// The least significant byte of extended result code is the primary result code
+	if primary := sqliteErr.Code() & 0xff; primary == sqlite3.SQLITE_BUSY || primary == sqlite3.SQLITE_LOCKED {
+		return exception.Throw(err, exception.WithType(exception.Conflict), exception.WithTitle("transaction conflict"), detail)
+	}
+
+	return exception.Throw(err, detail)
+}
+
+// Retryable on SQLITE_BUSY and SQLITE_LOCKED
+func (dialect) Retryable(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	primary := sqliteErr.Code() & 0xff
+	return primary == sqlite3.SQLITE_BUSY || primary == sqlite3.SQLITE_LOCKED
+}
+
+// MaxPlaceholders is SQLITE_MAX_VARIABLE_NUMBER default since sqlite 3.32.0
+func (dialect) MaxPlaceholders() int {
+	return 32766
+}
+
+// Upsert using ON CONFLICT DO UPDATE the same as postgres, it require conflictColumns as the conflict target
+func (dialect) Upsert(conflictColumns, updateColumns []string) (string, exception.Exception) {
+	return db.Postgres.Upsert(conflictColumns, updateColumns)
+}
+
+// Returning is supported since sqlite 3.35.0
+func (dialect) Returning() bool {
+	return true
+}
+
+// CreateTable followed by CREATE INDEX IF NOT EXISTS of each index
+func (dialect) CreateTable(table db.Table) []string {
+	definitions := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		definitions = append(definitions, column.Definition(columnTypes[column.Type]))
+	}
+
+	statements := []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table.Name, strings.Join(definitions, ", "))}
+	for _, index := range table.Indexes {
+		statements = append(statements, index.Create(table.Name))
+	}
+
+	return statements
+}
+
+var columnTypes = map[db.ColumnType]string{
+	db.AutoIncrement: "INTEGER PRIMARY KEY AUTOINCREMENT",
+	db.String:        "TEXT",
+	db.Text:          "TEXT",
+	db.Bytes:         "BLOB",
+	db.Integer:       "INTEGER",
+	db.Timestamp:     "DATETIME",
+}
+
+// StaleStatement on broken connection, SQLITE_SCHEMA or when table or column of the statement is dropped
+func (dialect) StaleStatement(err error) bool {
+	if db.BadConnection(err) {
+		return true
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	if sqliteErr.Code()&0xff == sqlite3.SQLITE_SCHEMA {
+		return true
+	}
+
+	return strings.Contains(sqliteErr.Error(), "no such table") || strings.Contains(sqliteErr.Error(), "no such column")
+}
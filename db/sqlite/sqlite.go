@@ -0,0 +1,26 @@
+// Package sqlite provide embedded sqlite dialect using pure go modernc.org/sqlite.
+// It is separated from package db so the driver is only linked into binary which import this package.
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// Memory used as Config.Name to fabricate in-memory sqlite database
+const Memory = ":memory:"
+
+// Dialect of sqlite, use it with db.WithDialect when adapting sqlite connection opened by sql.Open
+var Dialect db.Dialect = dialect{}
+
+// Fabricate will fabricate sqlite connection and wrap it into SQL interfaces.
+// Config name is the database file path, use Memory to fabricate in-memory database which live until the instance is closed.
+func Fabricate(instanceName string, config db.Config, opts ...db.Option) (db.DB, exception.Exception) {
+	if config.Name == Memory {
+		config.Name = fmt.Sprintf("file:%s?mode=memory&cache=shared", instanceName)
+	}
+
+	return db.Fabricate(instanceName, Dialect, config, opts...)
+}
@@ -0,0 +1,97 @@
+package sqlite_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFabricate(t *testing.T) {
+	t.Run("In-memory database run real SQL", func(t *testing.T) {
+		ktx := kontext.Fabricate()
+
+		sqldb, err := sqlite.Fabricate("memory_db", db.Config{Name: sqlite.Memory})
+		assert.NotNil(t, sqldb)
+		assert.Nil(t, err)
+		assert.Equal(t, sqlite.Dialect, sqldb.Dialect())
+
+		_, err = sqldb.ExecContext(ktx, "create-users", "create table users (id integer primary key, name text not null unique)")
+		assert.Nil(t, err)
+
+		err = sqldb.Transaction(ktx, "insert-users", func(tx db.TX) exception.Exception {
+			_, err := tx.ExecContext(ktx, "insert-user", "insert into users (id, name) values (?, ?)", 1, "john")
+			return err
+		})
+		assert.Nil(t, err)
+
+		_, err = sqldb.ExecContext(ktx, "insert-user", "insert into users (id, name) values (?, ?)", 2, "john")
+		assert.NotNil(t, err)
+		assert.Equal(t, exception.Duplicated, err.Type())
+
+		_, err = sqldb.ExecContext(ktx, "insert-user", "insert into users (id, name) values (?, ?)", 3, nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, exception.BadInput, err.Type())
+
+		var name string
+		assert.Nil(t, sqldb.QueryRowContext(ktx, "find-user", "select name from users where id = ?", 1).Scan(&name))
+		assert.Equal(t, "john", name)
+
+		err = sqldb.QueryRowContext(ktx, "find-user", "select name from users where id = ?", 2).Scan(&name)
+		assert.Equal(t, exception.NotFound, err.Type())
+
+		otherdb, err := sqlite.Fabricate("other_memory_db", db.Config{Name: sqlite.Memory})
+		assert.Nil(t, err)
+		_, err = otherdb.QueryContext(ktx, "list-users", "select id from users")
+		assert.NotNil(t, err)
+
+		sqldbvalue, err := db.GetInstance("memory_db")
+		assert.NotNil(t, sqldbvalue)
+		assert.Nil(t, err)
+	})
+
+	t.Run("File database", func(t *testing.T) {
+		ktx := kontext.Fabricate()
+
+		sqldb, err := sqlite.Fabricate("file_db", db.Config{Name: filepath.Join(t.TempDir(), "test.db")})
+		assert.Nil(t, err)
+		assert.Nil(t, sqldb.Ping(ktx))
+
+		assert.Equal(t, 0, len(db.CloseAll()))
+	})
+}
+
+func TestDialect(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("DSN", func(t *testing.T) {
+		dsn, exc := db.DSN(sqlite.Dialect, db.Config{Name: "test.db"}, db.WithParams(map[string]string{"_txlock": "immediate"}))
+		assert.Nil(t, exc)
+		assert.Equal(t, "file:test.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate", dsn)
+	})
+
+	t.Run("When driver error is returned it will be classified by sqlite result code", func(t *testing.T) {
+		sqldb, exc := sqlite.Fabricate("dialect_db", db.Config{Name: sqlite.Memory})
+		assert.Nil(t, exc)
+		defer sqldb.Eject().Close()
+
+		_, exc = sqldb.ExecContext(ktx, "create-users", "create table users (id integer primary key)")
+		assert.Nil(t, exc)
+
+		_, exc = sqldb.ExecContext(ktx, "insert-user", "insert into users (id) values (1), (1)")
+		assert.Equal(t, exception.Duplicated, exc.Type())
+		assert.False(t, sqlite.Dialect.Retryable(exc))
+		assert.False(t, sqlite.Dialect.StaleStatement(exc))
+
+		_, exc = sqldb.QueryContext(ktx, "list-orders", "select id from orders")
+		assert.True(t, sqlite.Dialect.StaleStatement(exc))
+
+		assert.Equal(t, exception.Unexpected, sqlite.Dialect.Translate(errors.New("unexpected error")).Type())
+		assert.False(t, sqlite.Dialect.Retryable(errors.New("unexpected error")))
+	})
+}
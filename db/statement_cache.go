@@ -0,0 +1,249 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/kodefluence/monorepo/exception"
+)
+
+// WithStatementCache prepare each query once and reuse the prepared statement in the next execution of the same queryKey and query, up to size statements.
+// Least recently used statement is evicted when the cache is full and statement is dropped from the cache when its execution failed because it is stale, see Dialect StaleStatement.
+// Evicted statement is closed once every running execution using it is finished.
+// Inside transaction the cached statement is bound into the transaction once using StmtContext and reused until the transaction ends,
+// even when it is evicted from the cache in the meantime. Statements is cached per adapter, default to disabled.
+func WithStatementCache(size int) Option {
+	return func(c *Config) {
+		c.statementCacheSize = size
+	}
+}
+
+type statementKey struct {
+	queryKey string
+	query    string
+}
+
+// statementEntry is reference counted so the statement is only closed when it is evicted and no execution is using it
+type statementEntry struct {
+	key     statementKey
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// statementCache is LRU cache of prepared statement
+type statementCache struct {
+	db      *sql.DB
+	dialect Dialect
+	size    int
+	mutex   sync.Mutex
+	order   *list.List
+	entries map[statementKey]*list.Element
+}
+
+func newStatementCache(db *sql.DB, dialect Dialect, size int) *statementCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &statementCache{db: db, dialect: dialect, size: size, order: list.New(), entries: map[statementKey]*list.Element{}}
+}
+
+// acquire return cached entry or prepare new one, the entry must be released after the execution
+func (c *statementCache) acquire(ctx context.Context, key statementKey) (*statementEntry, error) {
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		entry := element.Value.(*statementEntry)
+		entry.refs++
+		c.mutex.Unlock()
+		return entry, nil
+	}
+	c.mutex.Unlock()
+
+	stmt, err := c.db.PrepareContext(ctx, key.query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Other goroutine might prepare the same statement in the meantime
+	if element, ok := c.entries[key]; ok {
+		_ = stmt.Close()
+		c.order.MoveToFront(element)
+		entry := element.Value.(*statementEntry)
+		entry.refs++
+		return entry, nil
+	}
+
+	entry := &statementEntry{key: key, stmt: stmt, refs: 1}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.evict(c.order.Back())
+	}
+
+	return entry, nil
+}
+
+// release entry acquired by acquire, evicted entry is closed by the last release.
+// Rows which is still open keep its own reference of the statement inside database/sql, so the entry can be released right after the query is started.
+func (c *statementCache) release(entry *statementEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+// invalidate evict entry when err mean the statement is stale, entry which is already replaced by other goroutine is left as it is
+func (c *statementCache) invalidate(entry *statementEntry, err error) {
+	if err == nil || !c.dialect.StaleStatement(err) {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok && element.Value.(*statementEntry) == entry {
+		c.evict(element)
+	}
+}
+
+func (c *statementCache) evict(element *list.Element) {
+	entry := element.Value.(*statementEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+
+	entry.evicted = true
+	if entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+// txStatements is cached statements bound into single transaction, bound statement is closed by database/sql when the transaction ends
+type txStatements struct {
+	tx    *sql.Tx
+	mutex sync.Mutex
+	bound map[statementKey]*boundStatement
+}
+
+type boundStatement struct {
+	stmt  *sql.Stmt
+	entry *statementEntry
+}
+
+func newTxStatements(tx *sql.Tx) *txStatements {
+	return &txStatements{tx: tx, bound: map[statementKey]*boundStatement{}}
+}
+
+// statement return cached statement of key, bound into the transaction when tx is not nil. The returned release must be called after the execution.
+func (c *statementCache) statement(ctx context.Context, tx *txStatements, key statementKey) (*boundStatement, func(), error) {
+	if tx != nil {
+		tx.mutex.Lock()
+		bound, ok := tx.bound[key]
+		tx.mutex.Unlock()
+
+		if ok {
+			return bound, func() {}, nil
+		}
+	}
+
+	entry, err := c.acquire(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tx == nil {
+		return &boundStatement{stmt: entry.stmt, entry: entry}, func() { c.release(entry) }, nil
+	}
+
+	bound := &boundStatement{stmt: tx.tx.StmtContext(ctx, entry.stmt), entry: entry}
+
+	tx.mutex.Lock()
+	tx.bound[key] = bound
+	tx.mutex.Unlock()
+
+	return bound, func() { c.release(entry) }, nil
+}
+
+// invalidateBound drop bound statement from the transaction and its entry from the cache when err mean the statement is stale
+func (c *statementCache) invalidateBound(tx *txStatements, bound *boundStatement, err error) {
+	if err == nil || !c.dialect.StaleStatement(err) {
+		return
+	}
+
+	if tx != nil {
+		tx.mutex.Lock()
+		if tx.bound[bound.entry.key] == bound {
+			delete(tx.bound, bound.entry.key)
+		}
+		tx.mutex.Unlock()
+	}
+
+	c.invalidate(bound.entry, err)
+}
+
+func (c *statementCache) execContext(ctx context.Context, tx *txStatements, queryKey, query string, args ...interface{}) (sql.Result, error) {
+	bound, release, err := c.statement(ctx, tx, statementKey{queryKey: queryKey, query: query})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err := bound.stmt.ExecContext(ctx, args...)
+	c.invalidateBound(tx, bound, err)
+
+	return result, err
+}
+
+func (c *statementCache) queryContext(ctx context.Context, tx *txStatements, queryKey, query string, args ...interface{}) (*sql.Rows, error) {
+	bound, release, err := c.statement(ctx, tx, statementKey{queryKey: queryKey, query: query})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := bound.stmt.QueryContext(ctx, args...)
+	c.invalidateBound(tx, bound, err)
+
+	return rows, err
+}
+
+// queryRowContext return row which drop the cached statement when scanning failed because the statement is stale,
+// prepare error is returned by Scan since sql.Row error only known when it is scanned
+func (c *statementCache) queryRowContext(ctx context.Context, tx *txStatements, queryKey, query string, args ...interface{}) Row {
+	bound, release, err := c.statement(ctx, tx, statementKey{queryKey: queryKey, query: query})
+	if err != nil {
+		return &statementRow{exc: c.dialect.Translate(err)}
+	}
+	defer release()
+
+	return &statementRow{Row: adaptRow(bound.stmt.QueryRowContext(ctx, args...), c.dialect), invalidate: func(err error) {
+		c.invalidateBound(tx, bound, err)
+	}}
+}
+
+type statementRow struct {
+	Row
+	exc        exception.Exception
+	invalidate func(err error)
+}
+
+func (r *statementRow) Scan(dest ...interface{}) exception.Exception {
+	if r.exc != nil {
+		return r.exc
+	}
+
+	exc := r.Row.Scan(dest...)
+	if exc != nil {
+		r.invalidate(exc)
+	}
+
+	return exc
+}
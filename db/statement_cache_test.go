@@ -0,0 +1,191 @@
+package db_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/kodefluence/monorepo/db"
+	"github.com/kodefluence/monorepo/db/sqlite"
+	"github.com/kodefluence/monorepo/exception"
+	"github.com/kodefluence/monorepo/kontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementCache(t *testing.T) {
+	ktx := kontext.Fabricate()
+
+	t.Run("When the same query executed several times it will be prepared once", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		update := mockDB.ExpectPrepare(`update users set name = \?`)
+		update.ExpectExec().WithArgs("john").WillReturnResult(sqlmock.NewResult(0, 1))
+		update.ExpectExec().WithArgs("jane").WillReturnResult(sqlmock.NewResult(0, 1))
+		list := mockDB.ExpectPrepare(`select id from users`)
+		list.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		list.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mockDB.ExpectBegin()
+		update.ExpectExec().WithArgs("doe").WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		adapter := db.Adapt(sqldb, db.WithStatementCache(10))
+
+		for _, name := range []string{"john", "jane"} {
+			_, exc := adapter.ExecContext(ktx, "update-user", "update users set name = ?", name)
+			assert.Nil(t, exc)
+		}
+
+		rows, exc := adapter.QueryContext(ktx, "list-users", "select id from users")
+		assert.Nil(t, exc)
+		assert.Nil(t, rows.Close())
+
+		var id int
+		assert.Nil(t, adapter.QueryRowContext(ktx, "list-users", "select id from users").Scan(&id))
+		assert.Equal(t, 1, id)
+
+		exc = adapter.Transaction(ktx, "update-user", func(tx db.TX) exception.Exception {
+			_, exc := tx.ExecContext(ktx, "update-user", "update users set name = ?", "doe")
+			return exc
+		})
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When the same query executed several times inside transaction it will be bound into the transaction once", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectPrepare(`delete from users`)
+		mockDB.ExpectPrepare(`delete from users`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectPrepare(`delete from posts`)
+		mockDB.ExpectPrepare(`delete from posts`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectExec(`delete from users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectExec(`delete from posts`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		adapter := db.Adapt(sqldb, db.WithStatementCache(1))
+
+		exc := adapter.Transaction(ktx, "delete", func(tx db.TX) exception.Exception {
+			for _, query := range []string{"delete from users", "delete from posts", "delete from users", "delete from posts"} {
+				if _, exc := tx.ExecContext(ktx, "delete", query); exc != nil {
+					return exc
+				}
+			}
+
+			return nil
+		})
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When the cache is full it will close the least recently used statement", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectPrepare(`delete from users`).WillBeClosed().ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectPrepare(`delete from posts`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectPrepare(`delete from users`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+
+		adapter := db.Adapt(sqldb, db.WithStatementCache(1))
+
+		for _, query := range []string{"delete from users", "delete from posts", "delete from users"} {
+			_, exc := adapter.ExecContext(ktx, "delete", query)
+			assert.Nil(t, exc)
+		}
+
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When statement is stale it will be prepared again in the next execution", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		mockDB.ExpectPrepare(`select id from users`).WillBeClosed().ExpectQuery().WillReturnError(&mysql.MySQLError{Number: 1146, Message: "Table 'users' doesn't exist"})
+		mockDB.ExpectPrepare(`select id from users`).ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mockDB.ExpectPrepare(`update users`).WillReturnError(errors.New("unexpected error"))
+
+		adapter := db.Adapt(sqldb, db.WithStatementCache(10))
+
+		var id int
+		assert.NotNil(t, adapter.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id))
+		assert.Nil(t, adapter.QueryRowContext(ktx, "find-user", "select id from users").Scan(&id))
+		assert.Equal(t, 1, id)
+
+		_, exc := adapter.ExecContext(ktx, "update-user", "update users")
+		assert.Equal(t, exception.Unexpected, exc.Type())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When statement execution failed for ordinary reason it will keep the statement", func(t *testing.T) {
+		sqldb, mockDB, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer sqldb.Close()
+
+		insert := mockDB.ExpectPrepare(`insert into users`)
+		insert.ExpectExec().WithArgs("john").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'john'"})
+		insert.ExpectExec().WithArgs("jane").WillReturnResult(sqlmock.NewResult(2, 1))
+
+		adapter := db.Adapt(sqldb, db.WithStatementCache(10))
+
+		_, exc := adapter.ExecContext(ktx, "insert-user", "insert into users (name) values (?)", "john")
+		assert.Equal(t, exception.Duplicated, exc.Type())
+
+		_, exc = adapter.ExecContext(ktx, "insert-user", "insert into users (name) values (?)", "jane")
+		assert.Nil(t, exc)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("When statement is evicted while other goroutines use it it will be closed after they are finished", func(t *testing.T) {
+		sqldb, exc := sqlite.Fabricate("statement_cache_db", db.Config{Name: sqlite.Memory}, db.WithStatementCache(2))
+		assert.Nil(t, exc)
+		defer sqldb.Eject().Close()
+
+		var wg sync.WaitGroup
+		var failures int64
+		for i := 0; i < 64; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				for j := 0; j < 50; j++ {
+					key := (i + j) % 16
+
+					var value int
+					if exc := sqldb.QueryRowContext(ktx, fmt.Sprintf("select-%d", key), fmt.Sprintf("SELECT %d", key)).Scan(&value); exc != nil || value != key {
+						atomic.AddInt64(&failures, 1)
+					}
+
+					rows, exc := sqldb.QueryContext(ktx, fmt.Sprintf("select-%d", key), fmt.Sprintf("SELECT %d", key))
+					if exc != nil {
+						atomic.AddInt64(&failures, 1)
+						continue
+					}
+					_ = rows.Close()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(0), failures)
+	})
+}
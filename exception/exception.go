@@ -49,3 +49,8 @@ func (e *Error) Detail() string {
 func (e *Error) Title() string {
 	return e.config.title
 }
+
+// Unwrap return the original error
+func (e *Error) Unwrap() error {
+	return e.err
+}
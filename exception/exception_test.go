@@ -22,6 +22,7 @@ func TestException(t *testing.T) {
 		assert.Equal(t, detail, exc.Detail())
 		assert.Equal(t, title, exc.Title())
 		assert.Equal(t, err.Error(), exc.Error())
+		assert.True(t, errors.Is(exc, err))
 	})
 
 	t.Run("Type", func(t *testing.T) {
@@ -29,5 +30,7 @@ func TestException(t *testing.T) {
 		assert.Equal(t, "not found", exception.NotFound.String())
 		assert.Equal(t, "duplicated", exception.Duplicated.String())
 		assert.Equal(t, "bad input", exception.BadInput.String())
+		assert.Equal(t, "conflict", exception.Conflict.String())
+		assert.Equal(t, "unavailable", exception.Unavailable.String())
 	})
 }
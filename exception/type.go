@@ -16,6 +16,10 @@ const (
 	Unauthorized
 	// Forbidden throwd when there is unexpected access from the caller
 	Forbidden
+	// Conflict throwed when there is concurrent process conflicting with the current one, e.g. deadlock or lock wait timeout
+	Conflict
+	// Unavailable throwed when there is dependency that can not be reached, e.g. lost database connection
+	Unavailable
 )
 
 func (t Type) String() string {
@@ -26,5 +30,7 @@ func (t Type) String() string {
 		"bad input",
 		"unauthorized",
 		"forbidden",
+		"conflict",
+		"unavailable",
 	}[t]
 }
@@ -6,6 +6,7 @@ type Body struct {
 	Data   interface{} `json:"data,omitempty"`
 	Errors Errors      `json:"errors,omitempty"`
 	Meta   Meta        `json:"meta,omitempty"`
+	Links  Links       `json:"links,omitempty"`
 }
 
 func (b *Body) HTTPStatus() int {
@@ -14,6 +15,9 @@ func (b *Body) HTTPStatus() int {
 
 type Meta map[string]interface{}
 
+// Links of the document such as self, prev and next pagination links
+type Links map[string]string
+
 // Source represents references to the primary source of the error
 type Source struct {
 	Pointer   string `json:"pointer,omitempty"`   // JSON Pointer to the value in the request document that caused the error
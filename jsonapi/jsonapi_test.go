@@ -175,4 +175,14 @@ func TestJSONAPI(t *testing.T) {
 		// The source should contain only the header field since it was the last one set
 		assert.Contains(t, string(b), "\"source\":{\"pointer\":\"/data\",\"parameter\":\"sort\",\"header\":\"X-Custom\"}")
 	})
+
+	t.Run("Links is merged and printed in json format", func(t *testing.T) {
+		response := jsonapi.BuildResponse(
+			jsonapi.WithLinks(jsonapi.Links{"self": "/users"}),
+			jsonapi.WithLinks(jsonapi.Links{"next": "/users?page%5Bafter%5D=abc"}),
+		)
+
+		b, _ := json.Marshal(response)
+		assert.Equal(t, `{"links":{"next":"/users?page%5Bafter%5D=abc","self":"/users"}}`, string(b))
+	})
 }
@@ -78,3 +78,15 @@ func WithMeta(key string, field interface{}) Option {
 		b.Meta[key] = field
 	}
 }
+
+// WithLinks merge links into the document links
+func WithLinks(links Links) Option {
+	return func(b *Body) {
+		if b.Links == nil {
+			b.Links = Links{}
+		}
+		for key, href := range links {
+			b.Links[key] = href
+		}
+	}
+}